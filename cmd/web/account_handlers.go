@@ -3,7 +3,9 @@ package main
 import (
 	"errors"
 	"net/http"
+	"net/url"
 	"snippetbox/internal/models"
+	"snippetbox/internal/templates"
 	"snippetbox/internal/validator"
 )
 
@@ -15,7 +17,7 @@ type UpdatePasswordForm struct {
 }
 
 func (app *App) AccountView(w http.ResponseWriter, r *http.Request) {
-	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+	id := app.authenticatedUserID(r)
 
 	user, err := app.users.Get(id)
 
@@ -23,21 +25,61 @@ func (app *App) AccountView(w http.ResponseWriter, r *http.Request) {
 		if errors.Is(err, models.ErrNoRecord) {
 			http.Redirect(w, r, "/user/login", http.StatusSeeOther)
 		} else {
-			app.serverError(w, err)
+			app.serverError(w, r, err)
 		}
 		return
 	}
 
+	twoFactor, err := app.twoFactor.Enabled(id)
+
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
 	data := app.newTemplateData(r)
 
 	data.Account = user
+	data.TwoFactorEnabled = twoFactor
 
-	app.render(w, http.StatusOK, "account.tmpl.html", data)
+	app.render(w, r, http.StatusOK, "account.tmpl.html", data)
+}
+
+const snippetsPageSize = 10
+
+func (app *App) AccountSnippets(w http.ResponseWriter, r *http.Request) {
+	id := app.authenticatedUserID(r)
+
+	page := queryPage(r)
+	sort := r.URL.Query().Get("sort")
+
+	if !validator.PermittedValue(sort, models.SortCreated, models.SortExpires) {
+		sort = models.SortCreated
+	}
+
+	snippets, total, err := app.snippets.ByUser(id, sort, snippetsPageSize, (page-1)*snippetsPageSize)
+
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippets = snippets
+	data.Pagination = templates.NewPagination(page, snippetsPageSize, total, "/account/snippets", url.Values{"sort": {sort}})
+
+	app.render(w, r, http.StatusOK, "account_snippets.tmpl.html", data)
 }
 
 func (app *App) AccountPasswordUpdate(w http.ResponseWriter, r *http.Request) {
+	// token shouldnt be able to change password
+	if isTokenAuthenticated(r) {
+		app.clientError(w, http.StatusForbidden)
+		return
+	}
+
 	var form UpdatePasswordForm
-	id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+	id := app.authenticatedUserID(r)
 	err := app.DecodePostForm(r, &form)
 
 	if err != nil {
@@ -55,7 +97,7 @@ func (app *App) AccountPasswordUpdate(w http.ResponseWriter, r *http.Request) {
 	if !form.Valid() {
 		data := app.newTemplateData(r)
 		data.Form = form
-		app.render(w, http.StatusUnprocessableEntity, "password.tmpl.html", data)
+		app.render(w, r, http.StatusUnprocessableEntity, "password.tmpl.html", data)
 		return
 	}
 
@@ -66,10 +108,10 @@ func (app *App) AccountPasswordUpdate(w http.ResponseWriter, r *http.Request) {
 			form.AddFieldError("currentPassword", "Incorrect current password")
 			data := app.newTemplateData(r)
 			data.Form = form
-			app.render(w, http.StatusUnprocessableEntity, "password.tmpl.html", data)
+			app.render(w, r, http.StatusUnprocessableEntity, "password.tmpl.html", data)
 			return
 		} else {
-			app.serverError(w, err)
+			app.serverError(w, r, err)
 		}
 	}
 
@@ -78,7 +120,12 @@ func (app *App) AccountPasswordUpdate(w http.ResponseWriter, r *http.Request) {
 }
 
 func (app *App) AccountPasswordUpdateView(w http.ResponseWriter, r *http.Request) {
+	if isTokenAuthenticated(r) {
+		app.clientError(w, http.StatusForbidden)
+		return
+	}
+
 	data := app.newTemplateData(r)
 	data.Form = UpdatePasswordForm{}
-	app.render(w, http.StatusOK, "password.tmpl.html", data)
+	app.render(w, r, http.StatusOK, "password.tmpl.html", data)
 }
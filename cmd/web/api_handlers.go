@@ -0,0 +1,204 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"snippetbox/internal/highlight"
+	"snippetbox/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type snippetResponse struct {
+	Snippet *models.Snippet `json:"snippet"`
+}
+
+type snippetsResponse struct {
+	Snippets []*models.Snippet `json:"snippets"`
+}
+
+// apiSnippet copies snippet for response,
+// fork source is dropped unless viewer can see it
+func apiSnippet(snippet *models.Snippet, viewerID int) *models.Snippet {
+	s := *snippet
+
+	if !s.SourceVisibleTo(viewerID) {
+		s.Source = nil
+	}
+
+	return &s
+}
+
+func (app *App) APISnippetList(w http.ResponseWriter, r *http.Request) {
+	snippets, err := app.snippets.Latest()
+
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+
+	viewerID := app.authenticatedUserID(r)
+
+	for i, snippet := range snippets {
+		snippets[i] = apiSnippet(snippet, viewerID)
+	}
+
+	app.writeJSON(w, http.StatusOK, snippetsResponse{Snippets: snippets})
+}
+
+func (app *App) APISnippetGet(w http.ResponseWriter, r *http.Request) {
+	slug := chi.URLParam(r, "slug")
+
+	if !models.ValidSlug(slug) {
+		app.apiNotFound(w)
+		return
+	}
+
+	viewerID := app.authenticatedUserID(r)
+
+	snippet, err := app.snippets.GetBySlug(slug, viewerID)
+
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.apiNotFound(w)
+		} else {
+			app.apiServerError(w, r, err)
+		}
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, snippetResponse{Snippet: apiSnippet(snippet, viewerID)})
+}
+
+func (app *App) APISnippetCreate(w http.ResponseWriter, r *http.Request) {
+	form := SnippetCreateForm{
+		Language:   highlight.Plaintext,
+		Visibility: models.VisibilityPublic,
+	}
+
+	err := app.readJSON(w, r, &form)
+
+	if err != nil {
+		app.apiBadRequest(w, err)
+		return
+	}
+
+	form.validate(createExpires)
+
+	if !form.Valid() {
+		app.apiValidationError(w, form.FieldErrors)
+		return
+	}
+
+	userID := app.authenticatedUserID(r)
+
+	slug, err := app.snippets.Create(form.Title, form.Content, form.Language, form.Visibility, form.Expires, userID, 0)
+
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+
+	app.metrics.snippetsCreated.Inc()
+
+	snippet, err := app.snippets.GetBySlug(slug, userID)
+
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", "/api/v1/snippets/"+slug)
+	app.writeJSON(w, http.StatusCreated, snippetResponse{Snippet: apiSnippet(snippet, userID)})
+}
+
+func (app *App) APISnippetUpdate(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.apiOwnedSnippet(w, r)
+
+	if !ok {
+		return
+	}
+
+	// omitted title, content, language, visibility and expires keep current values
+	form := SnippetCreateForm{
+		Title:      snippet.Title,
+		Content:    snippet.Content,
+		Language:   snippet.Language,
+		Visibility: snippet.Visibility,
+	}
+
+	err := app.readJSON(w, r, &form)
+
+	if err != nil {
+		app.apiBadRequest(w, err)
+		return
+	}
+
+	form.validate(editExpires)
+
+	if !form.Valid() {
+		app.apiValidationError(w, form.FieldErrors)
+		return
+	}
+
+	err = app.snippets.Update(snippet.ID, form.Title, form.Content, form.Language, form.Visibility, form.Expires, app.authenticatedUserID(r))
+
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.apiNotFound(w)
+		} else {
+			app.apiServerError(w, r, err)
+		}
+		return
+	}
+
+	snippet, err = app.snippets.GetBySlug(snippet.Slug, snippet.UserID)
+
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, snippetResponse{Snippet: apiSnippet(snippet, snippet.UserID)})
+}
+
+func (app *App) APISnippetDelete(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.apiOwnedSnippet(w, r)
+
+	if !ok {
+		return
+	}
+
+	err := app.snippets.Delete(snippet.ID)
+
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.apiNotFound(w)
+		} else {
+			app.apiServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// apiOwnedSnippet is snippetForOwner for api handlers.
+// On failure response is already written.
+func (app *App) apiOwnedSnippet(w http.ResponseWriter, r *http.Request) (*models.Snippet, bool) {
+	snippet, err := app.snippetForOwner(r)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrNoRecord):
+			app.apiNotFound(w)
+		case errors.Is(err, errNotOwner):
+			app.apiClientError(w, http.StatusForbidden)
+		default:
+			app.apiServerError(w, r, err)
+		}
+		return nil, false
+	}
+
+	return snippet, true
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"snippetbox/internal/models/mocks"
+	"snippetbox/internal/tests"
+	"strings"
+	"testing"
+)
+
+func Test_APISnippetGet(t *testing.T) {
+	app := newTestApp(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	testCases := []struct {
+		name       string
+		url        string
+		expCode    int
+		expBody    string
+		expMissing string
+	}{
+		{
+			name:    "List",
+			url:     "/api/v1/snippets",
+			expCode: http.StatusOK,
+			expBody: `"title":"Snippet Title"`,
+		},
+		{
+			name:    "Valid slug",
+			url:     "/api/v1/snippets/mockSnippet1",
+			expCode: http.StatusOK,
+			expBody: `"content":"Snippet Content"`,
+		},
+		{
+			name:       "Hides sequential ID",
+			url:        "/api/v1/snippets/mockSnippet1",
+			expCode:    http.StatusOK,
+			expBody:    `"snippet":{"slug":"mockSnippet1"`,
+			expMissing: `"id"`,
+		},
+		{
+			name:    "Fork source as slug",
+			url:     "/api/v1/snippets/mockSnippet2",
+			expCode: http.StatusOK,
+			expBody: `"forked_from":"mockSnippet1"`,
+		},
+		{
+			name:       "Hides unlisted fork source",
+			url:        "/api/v1/snippets/mockSnippet7",
+			expCode:    http.StatusOK,
+			expBody:    `"slug":"mockSnippet7"`,
+			expMissing: `"forked_from"`,
+		},
+		{
+			name:    "Non-existent slug",
+			url:     "/api/v1/snippets/mockSnippet9",
+			expCode: http.StatusNotFound,
+			expBody: `{"error":{"status":404,"message":"Not Found"}}`,
+		},
+		{
+			name:    "Numeric ID",
+			url:     "/api/v1/snippets/1",
+			expCode: http.StatusNotFound,
+			expBody: `"status":404`,
+		},
+		{
+			name:    "Unknown route",
+			url:     "/api/v1/unknown",
+			expCode: http.StatusNotFound,
+			expBody: `"status":404`,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			code, header, body := ts.get(t, tt.url)
+
+			tests.Equal(t, code, tt.expCode)
+			tests.Equal(t, header.Get("Content-Type"), "application/json")
+			tests.StringContains(t, body, tt.expBody)
+
+			if tt.expMissing != "" && strings.Contains(body, tt.expMissing) {
+				t.Errorf("body contains %q", tt.expMissing)
+			}
+		})
+	}
+}
+
+func Test_APISnippetCreate(t *testing.T) {
+	app := newTestApp(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	t.Run("Unauthenticated", func(t *testing.T) {
+		code, _, body := ts.doJSON(t, http.MethodPost, "/api/v1/snippets", `{}`)
+
+		tests.Equal(t, code, http.StatusUnauthorized)
+		tests.StringContains(t, body, `"status":401`)
+	})
+
+	ts.login(t)
+
+	testCases := []struct {
+		name    string
+		body    string
+		expCode int
+		expBody string
+	}{
+		{
+			name:    "Valid",
+			body:    `{"title":"Title","content":"Content","language":"go","expires":7}`,
+			expCode: http.StatusCreated,
+			expBody: `"snippet":{`,
+		},
+		{
+			name:    "Malformed json",
+			body:    `{"title":`,
+			expCode: http.StatusBadRequest,
+			expBody: "body contains malformed json",
+		},
+		{
+			name:    "Unknown field",
+			body:    `{"title":"Title","author":"Bob"}`,
+			expCode: http.StatusBadRequest,
+			expBody: `body contains unknown field \"author\"`,
+		},
+		{
+			name:    "Wrong type",
+			body:    `{"title":"Title","expires":"week"}`,
+			expCode: http.StatusBadRequest,
+			expBody: `body contains wrong type for field \"expires\"`,
+		},
+		{
+			name:    "Validation errors",
+			body:    `{"title":"","content":"Content","language":"cobol","expires":2}`,
+			expCode: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.doJSON(t, http.MethodPost, "/api/v1/snippets", tt.body)
+
+			tests.Equal(t, code, tt.expCode)
+			tests.StringContains(t, body, tt.expBody)
+		})
+	}
+
+	t.Run("Field errors", func(t *testing.T) {
+		_, _, body := ts.doJSON(t, http.MethodPost, "/api/v1/snippets", `{"title":"","content":"Content","language":"cobol","expires":2}`)
+
+		var resp apiErrorBody
+
+		err := json.Unmarshal([]byte(body), &resp)
+
+		tests.NilError(t, err)
+		tests.Equal(t, resp.Error.Status, http.StatusUnprocessableEntity)
+		tests.Equal(t, resp.Error.Fields["title"], "This field cant be empty")
+		tests.Equal(t, resp.Error.Fields["language"], "This language is not supported")
+		tests.Equal(t, resp.Error.Fields["expires"], "This field must be 1, 7 or 365")
+	})
+
+	t.Run("Wrong content type", func(t *testing.T) {
+		code, _, _ := ts.postForm(t, "/api/v1/snippets", nil)
+
+		tests.Equal(t, code, http.StatusUnsupportedMediaType)
+	})
+}
+
+func Test_APISnippetUpdateDelete(t *testing.T) {
+	app := newTestApp(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.login(t)
+
+	testCases := []struct {
+		name    string
+		method  string
+		url     string
+		body    string
+		expCode int
+	}{
+		{
+			name:    "Update owner",
+			method:  http.MethodPut,
+			url:     "/api/v1/snippets/mockSnippet1",
+			body:    `{"title":"New title","expires":7}`,
+			expCode: http.StatusOK,
+		},
+		{
+			name:    "Update keeps expiry",
+			method:  http.MethodPut,
+			url:     "/api/v1/snippets/mockSnippet1",
+			body:    `{"title":"New title"}`,
+			expCode: http.StatusOK,
+		},
+		{
+			name:    "Update not owner",
+			method:  http.MethodPut,
+			url:     "/api/v1/snippets/mockSnippet2",
+			body:    `{"title":"New title","expires":7}`,
+			expCode: http.StatusForbidden,
+		},
+		{
+			name:    "Update non-existent",
+			method:  http.MethodPut,
+			url:     "/api/v1/snippets/mockSnippet9",
+			body:    `{"title":"New title","expires":7}`,
+			expCode: http.StatusNotFound,
+		},
+		{
+			name:    "Delete not owner",
+			method:  http.MethodDelete,
+			url:     "/api/v1/snippets/mockSnippet2",
+			expCode: http.StatusForbidden,
+		},
+		{
+			name:    "Delete owner",
+			method:  http.MethodDelete,
+			url:     "/api/v1/snippets/mockSnippet1",
+			expCode: http.StatusNoContent,
+		},
+		{
+			name:    "Method not allowed",
+			method:  http.MethodPatch,
+			url:     "/api/v1/snippets/mockSnippet1",
+			expCode: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, _ := ts.doJSON(t, tt.method, tt.url, tt.body)
+
+			tests.Equal(t, code, tt.expCode)
+		})
+	}
+}
+
+func Test_APIBearerToken(t *testing.T) {
+	app := newTestApp(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	testCases := []struct {
+		name    string
+		token   string
+		expCode int
+	}{
+		{
+			name:    "Valid token",
+			token:   mocks.ValidToken,
+			expCode: http.StatusCreated,
+		},
+		{
+			name:    "Invalid token",
+			token:   "sbx_invalid",
+			expCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{"title":"Title","content":"Content","expires":7}`
+
+			req, err := http.NewRequest(http.MethodPost, ts.URL+"/api/v1/snippets", strings.NewReader(body))
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+
+			rs, err := ts.Client().Do(req)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer rs.Body.Close()
+
+			tests.Equal(t, rs.StatusCode, tt.expCode)
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// max size of json request body
+const maxJSONBodyBytes = 1 << 20
+
+var errUnsupportedMediaType = errors.New("content type must be application/json")
+
+// apiErrorBody is envelope for every api error response
+type apiErrorBody struct {
+	Error apiError `json:"error"`
+}
+
+type apiError struct {
+	Status  int               `json:"status"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+func (app *App) writeJSON(w http.ResponseWriter, status int, data any) {
+	js, err := json.Marshal(data)
+
+	// handlers only pass encodable data, so this is programming error
+	if err != nil {
+		app.logger.Error("encoding json response", "error", err)
+		writeAPIServerError(w, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(js)
+	w.Write([]byte("\n"))
+}
+
+// readJSON decodes single json object from request body into dst
+func (app *App) readJSON(w http.ResponseWriter, r *http.Request, dst any) error {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	// also protects cookie authenticated requests from CSRF,
+	// browsers cant send json cross-origin without preflight
+	if mediaType != "application/json" {
+		return errUnsupportedMediaType
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxJSONBodyBytes)
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(dst)
+
+	if err != nil {
+		var syntaxError *json.SyntaxError
+		var typeError *json.UnmarshalTypeError
+		var maxBytesError *http.MaxBytesError
+		var invalidUnmarshalError *json.InvalidUnmarshalError
+
+		switch {
+		case errors.As(err, &syntaxError):
+			return fmt.Errorf("body contains malformed json at character %d", syntaxError.Offset)
+		case errors.Is(err, io.ErrUnexpectedEOF):
+			return errors.New("body contains malformed json")
+		case errors.As(err, &typeError):
+			return fmt.Errorf("body contains wrong type for field %q", typeError.Field)
+		case errors.Is(err, io.EOF):
+			return errors.New("body must not be empty")
+		case strings.HasPrefix(err.Error(), "json: unknown field "):
+			return fmt.Errorf("body contains unknown field %s", strings.TrimPrefix(err.Error(), "json: unknown field "))
+		case errors.As(err, &maxBytesError):
+			return fmt.Errorf("body must not be larger than %d bytes", maxBytesError.Limit)
+		case errors.As(err, &invalidUnmarshalError):
+			panic(err)
+		default:
+			return err
+		}
+	}
+
+	if dec.More() {
+		return errors.New("body must only contain a single json object")
+	}
+
+	return nil
+}
+
+func (app *App) apiErrorResponse(w http.ResponseWriter, status int, message string) {
+	app.writeJSON(w, status, apiErrorBody{
+		Error: apiError{Status: status, Message: message},
+	})
+}
+
+func (app *App) apiClientError(w http.ResponseWriter, status int) {
+	app.apiErrorResponse(w, status, http.StatusText(status))
+}
+
+func (app *App) apiNotFound(w http.ResponseWriter) {
+	app.apiClientError(w, http.StatusNotFound)
+}
+
+func (app *App) apiBadRequest(w http.ResponseWriter, err error) {
+	if errors.Is(err, errUnsupportedMediaType) {
+		app.apiErrorResponse(w, http.StatusUnsupportedMediaType, err.Error())
+		return
+	}
+
+	app.apiErrorResponse(w, http.StatusBadRequest, err.Error())
+}
+
+func (app *App) apiValidationError(w http.ResponseWriter, fields map[string]string) {
+	app.writeJSON(w, http.StatusUnprocessableEntity, apiErrorBody{
+		Error: apiError{
+			Status:  http.StatusUnprocessableEntity,
+			Message: "validation failed",
+			Fields:  fields,
+		},
+	})
+}
+
+func (app *App) apiServerError(w http.ResponseWriter, r *http.Request, err error) {
+	trace := app.logServerError(r, err)
+
+	message := http.StatusText(http.StatusInternalServerError)
+
+	if app.config.Debug {
+		message = trace
+	}
+
+	writeAPIServerError(w, message)
+}
+
+// writeAPIServerError doesnt use writeJSON so marshal error cant loop
+func writeAPIServerError(w http.ResponseWriter, message string) {
+	js, _ := json.Marshal(apiErrorBody{
+		Error: apiError{Status: http.StatusInternalServerError, Message: message},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusInternalServerError)
+	w.Write(js)
+	w.Write([]byte("\n"))
+}
@@ -23,7 +23,7 @@ type UserLoginForm struct {
 func (app *App) UserSignup(w http.ResponseWriter, r *http.Request) {
 	data := app.newTemplateData(r)
 	data.Form = UserSignupForm{}
-	app.render(w, http.StatusOK, "signup.tmpl.html", data)
+	app.render(w, r, http.StatusOK, "signup.tmpl.html", data)
 }
 
 func (app *App) UserSignupPost(w http.ResponseWriter, r *http.Request) {
@@ -45,11 +45,11 @@ func (app *App) UserSignupPost(w http.ResponseWriter, r *http.Request) {
 	if !form.Valid() {
 		data := app.newTemplateData(r)
 		data.Form = form
-		app.render(w, http.StatusUnprocessableEntity, "signup.tmpl.html", data)
+		app.render(w, r, http.StatusUnprocessableEntity, "signup.tmpl.html", data)
 		return
 	}
 
-	err = app.users.Create(form.Name, form.Email, form.Password)
+	id, err := app.users.Create(form.Name, form.Email, form.Password)
 
 	if err != nil {
 		if errors.Is(err, models.ErrDuplicateEmail) {
@@ -57,15 +57,23 @@ func (app *App) UserSignupPost(w http.ResponseWriter, r *http.Request) {
 
 			data := app.newTemplateData(r)
 			data.Form = form
-			app.render(w, http.StatusUnprocessableEntity, "signup.tmpl.html", data)
+			app.render(w, r, http.StatusUnprocessableEntity, "signup.tmpl.html", data)
 		} else {
-			app.serverError(w, err)
+			app.serverError(w, r, err)
 		}
 
 		return
 	}
 
-	app.sessionManager.Put(r.Context(), "flash", "User succesfully created!")
+	// account already exists, so failed email mustnt fail signup,
+	// new link can be requested on /user/verify/resend
+	user := &models.User{Id: id, Name: form.Name, Email: form.Email}
+
+	app.background(func() error {
+		return app.sendVerificationEmail(user)
+	})
+
+	app.sessionManager.Put(r.Context(), "flash", "User succesfully created! Check your email for link to verify your address, if it doesnt arrive request new one at /user/verify/resend.")
 
 	http.Redirect(w, r, "/user/login", http.StatusSeeOther)
 }
@@ -73,7 +81,7 @@ func (app *App) UserSignupPost(w http.ResponseWriter, r *http.Request) {
 func (app *App) UserLogin(w http.ResponseWriter, r *http.Request) {
 	data := app.newTemplateData(r)
 	data.Form = UserLoginForm{}
-	app.render(w, http.StatusOK, "login.tmpl.html", data)
+	app.render(w, r, http.StatusOK, "login.tmpl.html", data)
 }
 
 func (app *App) UserLoginPost(w http.ResponseWriter, r *http.Request) {
@@ -94,7 +102,25 @@ func (app *App) UserLoginPost(w http.ResponseWriter, r *http.Request) {
 	if !form.Valid() {
 		data := app.newTemplateData(r)
 		data.Form = form
-		app.render(w, http.StatusUnprocessableEntity, "login.tmpl.html", data)
+		app.render(w, r, http.StatusUnprocessableEntity, "login.tmpl.html", data)
+		return
+	}
+
+	ip := clientIP(r)
+
+	throttled, err := app.loginThrottled(form.Email, ip)
+
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if throttled {
+		form.AddNonFieldError(loginThrottledMessage)
+
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, r, http.StatusTooManyRequests, "login.tmpl.html", data)
 		return
 	}
 
@@ -103,27 +129,85 @@ func (app *App) UserLoginPost(w http.ResponseWriter, r *http.Request) {
 
 	if err != nil {
 		if errors.Is(err, models.ErrInvalidCredentials) {
+			app.metrics.logins.WithLabelValues(loginFailure).Inc()
+
+			if err := app.throttle.fail(form.Email, ip); err != nil {
+				app.serverError(w, r, err)
+				return
+			}
+
 			form.AddNonFieldError("Email or password is incorrect")
 
 			data := app.newTemplateData(r)
 			data.Form = form
-			app.render(w, http.StatusUnprocessableEntity, "login.tmpl.html", data)
+			app.render(w, r, http.StatusUnprocessableEntity, "login.tmpl.html", data)
+		} else if errors.Is(err, models.ErrEmailNotVerified) {
+			app.metrics.logins.WithLabelValues(loginFailure).Inc()
+			form.AddNonFieldError("Please verify your email address first")
+
+			data := app.newTemplateData(r)
+			data.Form = form
+			app.render(w, r, http.StatusUnprocessableEntity, "login.tmpl.html", data)
 		} else {
-			app.serverError(w, err)
+			app.serverError(w, r, err)
 		}
 		return
 	}
 
+	twoFactor, err := app.twoFactor.Enabled(id)
+
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if twoFactor {
+		app.startTwoFactorLogin(w, r, id)
+		return
+	}
+
+	err = app.throttle.succeed(form.Email)
+
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.logIn(w, r, id)
+}
+
+// same for locked out accounts and IPs, so it doesnt tell which one it is
+const loginThrottledMessage = "Too many failed login attempts, please try again later"
+
+// loginThrottled reports whether client has to wait before next login attempt
+func (app *App) loginThrottled(email, ip string) (bool, error) {
+	wait, err := app.throttle.wait(email, ip)
+
+	if err != nil {
+		return false, err
+	}
+
+	if wait > 0 {
+		app.metrics.logins.WithLabelValues(loginThrottled).Inc()
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// logIn puts user to session and redirects to page which required login
+func (app *App) logIn(w http.ResponseWriter, r *http.Request, id int) {
 	// renew session ID
-	err = app.sessionManager.RenewToken(r.Context())
+	err := app.sessionManager.RenewToken(r.Context())
 
 	if err != nil {
-		app.serverError(w, err)
+		app.serverError(w, r, err)
 		return
 	}
 
 	// add current user to session
 	app.sessionManager.Put(r.Context(), "authenticatedUserID", id)
+	app.metrics.logins.WithLabelValues(loginSuccess).Inc()
 
 	url := app.sessionManager.PopString(r.Context(), "redirectURL")
 
@@ -139,7 +223,7 @@ func (app *App) UserLogoutPost(w http.ResponseWriter, r *http.Request) {
 	err := app.sessionManager.RenewToken(r.Context())
 
 	if err != nil {
-		app.serverError(w, err)
+		app.serverError(w, r, err)
 		return
 	}
 
@@ -0,0 +1,449 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"os"
+	"snippetbox/internal/encrypt"
+	"snippetbox/internal/models"
+	"snippetbox/internal/signer"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+)
+
+// prefix of environment variables, flag -reap-batch is read from SNIPPETBOX_REAP_BATCH
+const envPrefix = "SNIPPETBOX_"
+
+// shown instead of secrets by -print-config
+const redacted = "REDACTED"
+
+// config holds all settings. Defaults are overridden by config file,
+// then by environment variables and finally by flags.
+type config struct {
+	Addr       string `yaml:"addr"`
+	DSN        string `yaml:"dsn"`
+	Debug      bool   `yaml:"debug"`
+	BcryptCost int    `yaml:"bcrypt_cost"`
+	// signs links sent by email
+	SecretKey string `yaml:"secret_key"`
+	// hex encoded AES-256 key encrypting TOTP secrets
+	EncryptionKey string `yaml:"encryption_key"`
+	// used to build absolute links in emails
+	BaseURL string `yaml:"base_url"`
+	// IPs or CIDRs of proxies whose X-Forwarded-For is trusted
+	TrustedProxies []string `yaml:"trusted_proxies"`
+	// IPs or CIDRs of clients allowed to scrape /metrics
+	MetricsAllow []string `yaml:"metrics_allow"`
+	TLS          struct {
+		Cert string `yaml:"cert"`
+		Key  string `yaml:"key"`
+	} `yaml:"tls"`
+	Session struct {
+		Lifetime time.Duration `yaml:"lifetime"`
+	} `yaml:"session"`
+	Server struct {
+		ReadTimeout     time.Duration `yaml:"read_timeout"`
+		WriteTimeout    time.Duration `yaml:"write_timeout"`
+		IdleTimeout     time.Duration `yaml:"idle_timeout"`
+		ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+		// how long /readyz fails before server stops accepting connections,
+		// gives load balancer time to notice
+		DrainDelay time.Duration `yaml:"drain_delay"`
+	} `yaml:"server"`
+	Reaper struct {
+		// 0 disables reaper
+		Interval time.Duration `yaml:"interval"`
+		// expired snippets can be renewed by their owner until grace passes
+		Grace time.Duration `yaml:"grace"`
+		Batch int           `yaml:"batch"`
+	} `yaml:"reaper"`
+	Login struct {
+		// failures before backoff starts
+		FreeAttempts int `yaml:"free_attempts"`
+		// wait after first throttled failure, doubles with each next one
+		Backoff        time.Duration `yaml:"backoff"`
+		LockoutAfter   int           `yaml:"lockout_after"`
+		IPLockoutAfter int           `yaml:"ip_lockout_after"`
+		Lockout        time.Duration `yaml:"lockout"`
+	} `yaml:"login"`
+	RateLimit struct {
+		User          rateLimitPolicy `yaml:"user"`
+		SnippetCreate rateLimitPolicy `yaml:"snippet_create"`
+		API           rateLimitPolicy `yaml:"api"`
+	} `yaml:"rate_limit"`
+	Mail struct {
+		// log, file or smtp
+		Transport string `yaml:"transport"`
+		From      string `yaml:"from"`
+		// where file transport writes messages
+		Dir  string `yaml:"dir"`
+		SMTP struct {
+			Host     string `yaml:"host"`
+			Port     int    `yaml:"port"`
+			Username string `yaml:"username"`
+			Password string `yaml:"password"`
+		} `yaml:"smtp"`
+		VerifyTTL time.Duration `yaml:"verify_ttl"`
+		ResetTTL  time.Duration `yaml:"reset_ttl"`
+	} `yaml:"mail"`
+}
+
+// rateLimitPolicy allows Requests per Period, all of them can be made at once.
+// 0 requests disables limit.
+type rateLimitPolicy struct {
+	Requests int           `yaml:"requests"`
+	Period   time.Duration `yaml:"period"`
+}
+
+func defaultConfig() *config {
+	cfg := &config{
+		Addr:       ":5000",
+		DSN:        "root:password@/snippetbox?parseTime=true",
+		BcryptCost: models.DefaultBcryptCost,
+		BaseURL:    "https://localhost:5000",
+		// only local scraper by default, metrics reveal traffic of site
+		MetricsAllow: []string{"127.0.0.1", "::1"},
+	}
+
+	cfg.TLS.Cert = "./tls/cert.pem"
+	cfg.TLS.Key = "./tls/key.pem"
+	cfg.Session.Lifetime = 12 * time.Hour
+	cfg.Server.ReadTimeout = 5 * time.Second
+	cfg.Server.WriteTimeout = 10 * time.Second
+	cfg.Server.IdleTimeout = time.Minute
+	cfg.Server.ShutdownTimeout = 20 * time.Second
+	cfg.Server.DrainDelay = 5 * time.Second
+	cfg.Reaper.Interval = time.Hour
+	cfg.Reaper.Grace = 30 * 24 * time.Hour
+	cfg.Reaper.Batch = 500
+	cfg.Login.FreeAttempts = 3
+	cfg.Login.Backoff = time.Second
+	cfg.Login.LockoutAfter = 10
+	cfg.Login.IPLockoutAfter = 100
+	cfg.Login.Lockout = 15 * time.Minute
+	cfg.RateLimit.User = rateLimitPolicy{Requests: 30, Period: time.Minute}
+	cfg.RateLimit.SnippetCreate = rateLimitPolicy{Requests: 20, Period: time.Minute}
+	cfg.RateLimit.API = rateLimitPolicy{Requests: 120, Period: time.Minute}
+	cfg.Mail.Transport = "log"
+	cfg.Mail.From = "Snippetbox <no-reply@localhost>"
+	cfg.Mail.Dir = "./mail"
+	cfg.Mail.SMTP.Port = 587
+	cfg.Mail.VerifyTTL = 24 * time.Hour
+	cfg.Mail.ResetTTL = time.Hour
+
+	return cfg
+}
+
+func (cfg *config) bindFlags(fs *flag.FlagSet) {
+	fs.StringVar(&cfg.Addr, "addr", cfg.Addr, "HTTP network address")
+	fs.StringVar(&cfg.DSN, "dsn", cfg.DSN, "MySQL connect name")
+	fs.BoolVar(&cfg.Debug, "debug", cfg.Debug, "Debug mode")
+	fs.IntVar(&cfg.BcryptCost, "bcrypt-cost", cfg.BcryptCost, "Cost of password hashing")
+	fs.StringVar(&cfg.SecretKey, "secret-key", cfg.SecretKey, "Key signing links sent by email, at least 32 characters")
+	fs.StringVar(&cfg.EncryptionKey, "encryption-key", cfg.EncryptionKey, "Hex encoded 32 byte key encrypting secrets in database")
+	fs.StringVar(&cfg.BaseURL, "base-url", cfg.BaseURL, "Public URL of site used in emails")
+	fs.Var((*listFlag)(&cfg.TrustedProxies), "trusted-proxies", "Comma separated IPs or CIDRs of proxies trusted to set X-Forwarded-For")
+	fs.Var((*listFlag)(&cfg.MetricsAllow), "metrics-allow", "Comma separated IPs or CIDRs of clients allowed to scrape /metrics")
+	fs.StringVar(&cfg.TLS.Cert, "tls-cert", cfg.TLS.Cert, "TLS certificate file")
+	fs.StringVar(&cfg.TLS.Key, "tls-key", cfg.TLS.Key, "TLS private key file")
+	fs.DurationVar(&cfg.Session.Lifetime, "session-lifetime", cfg.Session.Lifetime, "How long sessions last")
+	fs.DurationVar(&cfg.Server.ReadTimeout, "read-timeout", cfg.Server.ReadTimeout, "Max duration of reading request")
+	fs.DurationVar(&cfg.Server.WriteTimeout, "write-timeout", cfg.Server.WriteTimeout, "Max duration of writing response")
+	fs.DurationVar(&cfg.Server.IdleTimeout, "idle-timeout", cfg.Server.IdleTimeout, "How long keep-alive connections are kept idle")
+	fs.DurationVar(&cfg.Server.ShutdownTimeout, "shutdown-timeout", cfg.Server.ShutdownTimeout, "How long in-flight requests are waited for on shutdown")
+	fs.DurationVar(&cfg.Server.DrainDelay, "drain-delay", cfg.Server.DrainDelay, "How long readiness fails before shutdown begins")
+	fs.DurationVar(&cfg.Reaper.Interval, "reap-interval", cfg.Reaper.Interval, "How often expired snippets are deleted, 0 disables")
+	fs.DurationVar(&cfg.Reaper.Grace, "reap-grace", cfg.Reaper.Grace, "How long expired snippets are kept for renewal before deletion")
+	fs.IntVar(&cfg.Reaper.Batch, "reap-batch", cfg.Reaper.Batch, "Max snippets deleted by single query")
+	fs.IntVar(&cfg.Login.FreeAttempts, "login-free-attempts", cfg.Login.FreeAttempts, "Failed logins allowed before backoff")
+	fs.DurationVar(&cfg.Login.Backoff, "login-backoff", cfg.Login.Backoff, "First backoff after failed login, doubles with each failure")
+	fs.IntVar(&cfg.Login.LockoutAfter, "login-lockout-after", cfg.Login.LockoutAfter, "Failed logins before account is locked out")
+	fs.IntVar(&cfg.Login.IPLockoutAfter, "login-ip-lockout-after", cfg.Login.IPLockoutAfter, "Failed logins before client IP is locked out")
+	fs.DurationVar(&cfg.Login.Lockout, "login-lockout", cfg.Login.Lockout, "How long lockout lasts and failed logins are remembered")
+	cfg.RateLimit.User.bindFlags(fs, "user", "/user pages")
+	cfg.RateLimit.SnippetCreate.bindFlags(fs, "snippet-create", "snippet creation")
+	cfg.RateLimit.API.bindFlags(fs, "api", "API")
+	fs.StringVar(&cfg.Mail.Transport, "mail-transport", cfg.Mail.Transport, "How emails are sent: log, file or smtp")
+	fs.StringVar(&cfg.Mail.From, "mail-from", cfg.Mail.From, "Sender of emails")
+	fs.StringVar(&cfg.Mail.Dir, "mail-dir", cfg.Mail.Dir, "Directory emails are written to by file transport")
+	fs.StringVar(&cfg.Mail.SMTP.Host, "smtp-host", cfg.Mail.SMTP.Host, "SMTP server host")
+	fs.IntVar(&cfg.Mail.SMTP.Port, "smtp-port", cfg.Mail.SMTP.Port, "SMTP server port")
+	fs.StringVar(&cfg.Mail.SMTP.Username, "smtp-username", cfg.Mail.SMTP.Username, "SMTP username")
+	fs.StringVar(&cfg.Mail.SMTP.Password, "smtp-password", cfg.Mail.SMTP.Password, "SMTP password")
+	fs.DurationVar(&cfg.Mail.VerifyTTL, "verify-ttl", cfg.Mail.VerifyTTL, "How long email verification links are valid")
+	fs.DurationVar(&cfg.Mail.ResetTTL, "reset-ttl", cfg.Mail.ResetTTL, "How long password reset links are valid")
+}
+
+func (p *rateLimitPolicy) bindFlags(fs *flag.FlagSet, name, usage string) {
+	fs.IntVar(&p.Requests, "rate-limit-"+name+"-requests", p.Requests, "Requests to "+usage+" allowed per period, 0 disables limit")
+	fs.DurationVar(&p.Period, "rate-limit-"+name+"-period", p.Period, "Period of rate limit of "+usage)
+}
+
+// listFlag is comma separated flag value
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	*l = nil
+
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+
+	return nil
+}
+
+// loadConfig builds config from args (without program name) and environment.
+// Config file is given by -config flag or SNIPPETBOX_CONFIG variable.
+// Reports whether -print-config was requested.
+func loadConfig(args []string, lookupEnv func(string) (string, bool)) (*config, bool, error) {
+	cfg := defaultConfig()
+
+	fs := flag.NewFlagSet("web", flag.ContinueOnError)
+	configPath := fs.String("config", "", "YAML config file")
+	printConfig := fs.Bool("print-config", false, "Print effective config with secrets redacted and exit")
+	cfg.bindFlags(fs)
+
+	err := fs.Parse(args)
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	// flags have highest precedence but are parsed first to find config file,
+	// so they are remembered and applied again after file and environment
+	given := map[string]string{}
+
+	fs.Visit(func(f *flag.Flag) {
+		given[f.Name] = f.Value.String()
+	})
+
+	*cfg = *defaultConfig()
+
+	path := *configPath
+
+	if path == "" {
+		path, _ = lookupEnv(envPrefix + "CONFIG")
+	}
+
+	if path != "" {
+		err = cfg.loadFile(path)
+
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	var errs []error
+
+	fs.VisitAll(func(f *flag.Flag) {
+		if f.Name == "config" || f.Name == "print-config" {
+			return
+		}
+
+		name := envPrefix + strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_"))
+
+		if value, ok := lookupEnv(name); ok {
+			if err := fs.Set(f.Name, value); err != nil {
+				errs = append(errs, fmt.Errorf("environment variable %s: %w", name, err))
+			}
+		}
+	})
+
+	for name, value := range given {
+		fs.Set(name, value)
+	}
+
+	if len(errs) > 0 {
+		return nil, false, errors.Join(errs...)
+	}
+
+	return cfg, *printConfig, nil
+}
+
+// loadFile overrides config with values set in YAML file
+func (cfg *config) loadFile(path string) error {
+	f, err := os.Open(path)
+
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+
+	defer f.Close()
+
+	dec := yaml.NewDecoder(f)
+	// typos in keys would be silently ignored otherwise
+	dec.KnownFields(true)
+
+	err = dec.Decode(cfg)
+
+	if err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// validate returns all problems with config joined in single error
+func (cfg *config) validate() error {
+	var errs []error
+
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(cfg.Addr != "", "addr: must not be empty")
+
+	if err := cfg.validateDSN(); err != nil {
+		errs = append(errs, err)
+	}
+
+	check(cfg.BcryptCost >= bcrypt.MinCost && cfg.BcryptCost <= bcrypt.MaxCost,
+		"bcrypt_cost: must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+
+	check(len(cfg.SecretKey) >= signer.MinKeyLength,
+		"secret_key: must be at least %d characters", signer.MinKeyLength)
+
+	if _, err := encrypt.New(cfg.EncryptionKey); err != nil {
+		errs = append(errs, fmt.Errorf("encryption_key: %w", err))
+	}
+
+	baseURL, err := url.Parse(cfg.BaseURL)
+
+	if err != nil {
+		errs = append(errs, fmt.Errorf("base_url: %w", err))
+	} else {
+		check((baseURL.Scheme == "http" || baseURL.Scheme == "https") && baseURL.Host != "",
+			"base_url: must be absolute http or https URL")
+	}
+
+	if _, err := parseTrustedProxies(cfg.TrustedProxies); err != nil {
+		errs = append(errs, fmt.Errorf("trusted_proxies: %w", err))
+	}
+
+	if _, err := parseTrustedProxies(cfg.MetricsAllow); err != nil {
+		errs = append(errs, fmt.Errorf("metrics_allow: %w", err))
+	}
+
+	tlsFiles := []struct{ key, path string }{
+		{"tls.cert", cfg.TLS.Cert},
+		{"tls.key", cfg.TLS.Key},
+	}
+
+	for _, file := range tlsFiles {
+		if file.path == "" {
+			errs = append(errs, fmt.Errorf("%s: must not be empty", file.key))
+		} else if _, err := os.Stat(file.path); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", file.key, err))
+		}
+	}
+
+	check(cfg.Session.Lifetime > 0, "session.lifetime: must be positive")
+	check(cfg.Server.ReadTimeout > 0, "server.read_timeout: must be positive")
+	check(cfg.Server.WriteTimeout > 0, "server.write_timeout: must be positive")
+	check(cfg.Server.IdleTimeout > 0, "server.idle_timeout: must be positive")
+	check(cfg.Server.ShutdownTimeout > 0, "server.shutdown_timeout: must be positive")
+	check(cfg.Server.DrainDelay >= 0, "server.drain_delay: must not be negative")
+	check(cfg.Reaper.Interval >= 0, "reaper.interval: must not be negative")
+	check(cfg.Reaper.Grace >= 0, "reaper.grace: must not be negative")
+	check(cfg.Reaper.Batch > 0, "reaper.batch: must be positive")
+
+	check(cfg.Login.FreeAttempts >= 0, "login.free_attempts: must not be negative")
+	check(cfg.Login.Backoff > 0, "login.backoff: must be positive")
+	check(cfg.Login.LockoutAfter > cfg.Login.FreeAttempts, "login.lockout_after: must be greater than login.free_attempts")
+	check(cfg.Login.IPLockoutAfter > cfg.Login.FreeAttempts, "login.ip_lockout_after: must be greater than login.free_attempts")
+	check(cfg.Login.Lockout > 0, "login.lockout: must be positive")
+
+	rateLimits := []struct {
+		key    string
+		policy rateLimitPolicy
+	}{
+		{"rate_limit.user", cfg.RateLimit.User},
+		{"rate_limit.snippet_create", cfg.RateLimit.SnippetCreate},
+		{"rate_limit.api", cfg.RateLimit.API},
+	}
+
+	for _, limit := range rateLimits {
+		check(limit.policy.Requests >= 0, "%s.requests: must not be negative", limit.key)
+		check(limit.policy.Requests == 0 || limit.policy.Period > 0, "%s.period: must be positive", limit.key)
+	}
+
+	switch cfg.Mail.Transport {
+	case "log":
+	case "file":
+		check(cfg.Mail.Dir != "", "mail.dir: must not be empty")
+	case "smtp":
+		check(cfg.Mail.SMTP.Host != "", "mail.smtp.host: must not be empty")
+		check(cfg.Mail.SMTP.Port > 0 && cfg.Mail.SMTP.Port <= 65535, "mail.smtp.port: must be between 1 and 65535")
+	default:
+		errs = append(errs, fmt.Errorf("mail.transport: must be log, file or smtp, not %q", cfg.Mail.Transport))
+	}
+
+	_, err = mail.ParseAddress(cfg.Mail.From)
+
+	if err != nil {
+		errs = append(errs, fmt.Errorf("mail.from: %w", err))
+	}
+
+	check(cfg.Mail.VerifyTTL > 0, "mail.verify_ttl: must be positive")
+	check(cfg.Mail.ResetTTL >= time.Second, "mail.reset_ttl: must be at least 1s")
+
+	return errors.Join(errs...)
+}
+
+// validateDSN is used alone by commands that only need database
+func (cfg *config) validateDSN() error {
+	dsn, err := mysql.ParseDSN(cfg.DSN)
+
+	if err != nil {
+		return fmt.Errorf("dsn: %w", err)
+	}
+
+	if !dsn.ParseTime {
+		return errors.New("dsn: parseTime=true is required")
+	}
+
+	return nil
+}
+
+// redactedYAML returns YAML of config with passwords and keys hidden
+func (cfg *config) redactedYAML() ([]byte, error) {
+	c := *cfg
+
+	if c.SecretKey != "" {
+		c.SecretKey = redacted
+	}
+
+	if c.EncryptionKey != "" {
+		c.EncryptionKey = redacted
+	}
+
+	if c.Mail.SMTP.Password != "" {
+		c.Mail.SMTP.Password = redacted
+	}
+
+	dsn, err := mysql.ParseDSN(c.DSN)
+
+	if err != nil {
+		c.DSN = redacted
+	} else if dsn.Passwd != "" {
+		dsn.Passwd = redacted
+		c.DSN = dsn.FormatDSN()
+	}
+
+	return yaml.Marshal(c)
+}
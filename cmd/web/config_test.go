@@ -0,0 +1,233 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"snippetbox/internal/tests"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeFile creates file with content in test temp dir and returns its path
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+
+	err := os.WriteFile(path, []byte(content), 0o600)
+	tests.NilError(t, err)
+
+	return path
+}
+
+// mapEnv looks up variables in env instead of process environment
+func mapEnv(env map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+}
+
+func Test_loadConfig(t *testing.T) {
+	file := writeFile(t, "config.yaml", `
+addr: ":6000"
+session:
+  lifetime: 2h
+reaper:
+  batch: 100
+`)
+
+	testCases := []struct {
+		name        string
+		args        []string
+		env         map[string]string
+		expAddr     string
+		expLifetime time.Duration
+		expBatch    int
+	}{
+		{
+			name:        "Defaults",
+			expAddr:     ":5000",
+			expLifetime: 12 * time.Hour,
+			expBatch:    500,
+		},
+		{
+			name:        "File",
+			args:        []string{"-config", file},
+			expAddr:     ":6000",
+			expLifetime: 2 * time.Hour,
+			expBatch:    100,
+		},
+		{
+			name:        "File from environment",
+			env:         map[string]string{"SNIPPETBOX_CONFIG": file},
+			expAddr:     ":6000",
+			expLifetime: 2 * time.Hour,
+			expBatch:    100,
+		},
+		{
+			name:        "Environment overrides file",
+			args:        []string{"-config", file},
+			env:         map[string]string{"SNIPPETBOX_ADDR": ":7000", "SNIPPETBOX_REAP_BATCH": "50"},
+			expAddr:     ":7000",
+			expLifetime: 2 * time.Hour,
+			expBatch:    50,
+		},
+		{
+			name:        "Flags override environment",
+			args:        []string{"-config", file, "-addr", ":8000"},
+			env:         map[string]string{"SNIPPETBOX_ADDR": ":7000"},
+			expAddr:     ":8000",
+			expLifetime: 2 * time.Hour,
+			expBatch:    100,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, printConfig, err := loadConfig(tt.args, mapEnv(tt.env))
+
+			tests.NilError(t, err)
+			tests.Equal(t, printConfig, false)
+			tests.Equal(t, cfg.Addr, tt.expAddr)
+			tests.Equal(t, cfg.Session.Lifetime, tt.expLifetime)
+			tests.Equal(t, cfg.Reaper.Batch, tt.expBatch)
+		})
+	}
+
+	t.Run("List flag", func(t *testing.T) {
+		env := map[string]string{"SNIPPETBOX_TRUSTED_PROXIES": "10.0.0.1, 192.168.0.0/16"}
+		cfg, _, err := loadConfig(nil, mapEnv(env))
+
+		tests.NilError(t, err)
+		tests.Equal(t, len(cfg.TrustedProxies), 2)
+		tests.Equal(t, cfg.TrustedProxies[1], "192.168.0.0/16")
+	})
+
+	t.Run("Print config", func(t *testing.T) {
+		_, printConfig, err := loadConfig([]string{"-print-config"}, mapEnv(nil))
+
+		tests.NilError(t, err)
+		tests.Equal(t, printConfig, true)
+	})
+}
+
+func Test_loadConfigErrors(t *testing.T) {
+	testCases := []struct {
+		name   string
+		args   []string
+		env    map[string]string
+		expErr string
+	}{
+		{
+			name:   "Invalid flag",
+			args:   []string{"-read-timeout", "soon"},
+			expErr: `invalid value "soon" for flag -read-timeout`,
+		},
+		{
+			name:   "Invalid environment variable",
+			env:    map[string]string{"SNIPPETBOX_BCRYPT_COST": "high"},
+			expErr: "environment variable SNIPPETBOX_BCRYPT_COST",
+		},
+		{
+			name:   "Missing file",
+			args:   []string{"-config", "/no/such/config.yaml"},
+			expErr: "config file: open /no/such/config.yaml",
+		},
+		{
+			name:   "Unknown key",
+			args:   []string{"-config", writeFile(t, "typo.yaml", "adr: \":6000\"\n")},
+			expErr: "field adr not found",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := loadConfig(tt.args, mapEnv(tt.env))
+
+			if err == nil {
+				t.Fatal("expected error")
+			}
+
+			tests.StringContains(t, err.Error(), tt.expErr)
+		})
+	}
+}
+
+func Test_configValidate(t *testing.T) {
+	valid := func() *config {
+		cfg := defaultConfig()
+		cfg.SecretKey = testSecretKey
+		cfg.EncryptionKey = testEncryptionKey
+		cfg.TLS.Cert = writeFile(t, "cert.pem", "cert")
+		cfg.TLS.Key = writeFile(t, "key.pem", "key")
+		return cfg
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		tests.NilError(t, valid().validate())
+	})
+
+	cfg := valid()
+	cfg.DSN = "root:password@/snippetbox"
+	cfg.BcryptCost = 2
+	cfg.TLS.Key = "/no/such/key.pem"
+	cfg.Session.Lifetime = 0
+	cfg.Reaper.Batch = -1
+	cfg.SecretKey = "short"
+	cfg.EncryptionKey = "abcd"
+	cfg.BaseURL = "/relative"
+	cfg.Mail.Transport = "smtp"
+	cfg.TrustedProxies = []string{"10.0.0.0/33"}
+	cfg.MetricsAllow = []string{"localhost"}
+	cfg.RateLimit.API.Period = 0
+
+	err := cfg.validate()
+
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	expected := []string{
+		"dsn: parseTime=true is required",
+		"bcrypt_cost: must be between 4 and 31",
+		"tls.key: stat /no/such/key.pem",
+		"session.lifetime: must be positive",
+		"reaper.batch: must be positive",
+		"secret_key: must be at least 32 characters",
+		"encryption_key: encrypt: key must be 32 bytes, got 2",
+		"base_url: must be absolute http or https URL",
+		"mail.smtp.host: must not be empty",
+		"trusted_proxies: netip.ParsePrefix",
+		"metrics_allow: ParseAddr",
+		"rate_limit.api.period: must be positive",
+	}
+
+	for _, exp := range expected {
+		tests.StringContains(t, err.Error(), exp)
+	}
+
+	tests.Equal(t, strings.Count(err.Error(), "\n"), len(expected)-1)
+}
+
+func Test_configRedactedYAML(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.DSN = "web:s3cret@tcp(db:3306)/snippetbox?parseTime=true"
+	cfg.SecretKey = testSecretKey
+	cfg.EncryptionKey = testEncryptionKey
+	cfg.Mail.SMTP.Password = "smtp-s3cret"
+
+	out, err := cfg.redactedYAML()
+	tests.NilError(t, err)
+
+	for _, secret := range []string{"s3cret", testSecretKey, testEncryptionKey} {
+		if strings.Contains(string(out), secret) {
+			t.Errorf("secret %q not redacted in %s", secret, out)
+		}
+	}
+
+	tests.StringContains(t, string(out), "secret_key: REDACTED")
+	tests.StringContains(t, string(out), "password: REDACTED")
+
+	tests.StringContains(t, string(out), "dsn: web:REDACTED@tcp(db:3306)/snippetbox?parseTime=true")
+	tests.StringContains(t, string(out), "lifetime: 12h0m0s")
+}
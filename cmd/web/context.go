@@ -3,3 +3,13 @@ package main
 type contextKey string
 
 const isAuthenticatedContextKey = contextKey("isAuthenticated")
+const authenticatedUserIDContextKey = contextKey("authenticatedUserID")
+const isTokenAuthenticatedContextKey = contextKey("isTokenAuthenticated")
+const requestInfoContextKey = contextKey("requestInfo")
+
+// requestInfo is shared by all middlewares of request. Pointer is stored,
+// so values set by inner middlewares are seen by logRequests.
+type requestInfo struct {
+	id     string
+	userID int
+}
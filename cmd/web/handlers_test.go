@@ -2,12 +2,19 @@ package main
 
 import (
 	"bytes"
+	"errors"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"snippetbox/internal/mailer"
+	"snippetbox/internal/models"
+	"snippetbox/internal/models/mocks"
 	"snippetbox/internal/tests"
+	"strings"
 	"testing"
+	"time"
 )
 
 // unit
@@ -61,17 +68,65 @@ func Test_SnippetView(t *testing.T) {
 	defer ts.Close()
 
 	testCases := []struct {
-		name    string
-		url     string
-		expCode int
-		expBody string
+		name       string
+		url        string
+		expCode    int
+		expBody    string
+		expMissing string
 	}{
 		{
-			name:    "Valid ID",
-			url:     "/snippet/view/1",
+			name:    "Valid slug",
+			url:     "/snippet/view/mockSnippet1",
 			expCode: http.StatusOK,
 			expBody: "Snippet Content",
 		},
+		{
+			name:    "Valid ID redirects to slug",
+			url:     "/snippet/view/1",
+			expCode: http.StatusMovedPermanently,
+			expBody: "/snippet/view/mockSnippet1",
+		},
+		{
+			name:    "Unlisted ID doesnt redirect",
+			url:     "/snippet/view/6",
+			expCode: http.StatusNotFound,
+		},
+		{
+			name:    "Non-existent slug",
+			url:     "/snippet/view/mockSnippet9",
+			expCode: http.StatusNotFound,
+		},
+		{
+			name:    "Shows author",
+			url:     "/snippet/view/mockSnippet1",
+			expCode: http.StatusOK,
+			expBody: "by User",
+		},
+		{
+			name:    "Fork count skips private forks",
+			url:     "/snippet/view/mockSnippet1",
+			expCode: http.StatusOK,
+			expBody: "1 fork",
+		},
+		{
+			name:    "Shows fork source",
+			url:     "/snippet/view/mockSnippet2",
+			expCode: http.StatusOK,
+			expBody: "forked from <a href='/snippet/view/mockSnippet1'>mockSnippet1</a>",
+		},
+		{
+			name:       "Hides unlisted fork source",
+			url:        "/snippet/view/mockSnippet7",
+			expCode:    http.StatusOK,
+			expBody:    "Unlisted Fork Content",
+			expMissing: "forked from",
+		},
+		{
+			name:    "Highlighted content",
+			url:     "/snippet/view/mockSnippet1",
+			expCode: http.StatusOK,
+			expBody: `<pre class="chroma">`,
+		},
 		{
 			name:    "Negative ID",
 			url:     "/snippet/view/-1",
@@ -103,6 +158,10 @@ func Test_SnippetView(t *testing.T) {
 			if tt.expBody != "" {
 				tests.StringContains(t, body, tt.expBody)
 			}
+
+			if tt.expMissing != "" && strings.Contains(body, tt.expMissing) {
+				t.Errorf("body contains %q", tt.expMissing)
+			}
 		})
 	}
 }
@@ -261,4 +320,1289 @@ func Test_UserSignup(t *testing.T) {
 			}
 		})
 	}
+
+	app.wg.Wait()
+
+	msg, ok := app.mailer.(*mocks.Mailer).Last()
+
+	if !ok {
+		t.Fatal("verification email not sent")
+	}
+
+	tests.Equal(t, msg.To, Email)
+	tests.StringContains(t, msg.Body, "https://localhost:5000/user/verify?token=")
+}
+
+// failingMailer fails every send
+type failingMailer struct{}
+
+func (failingMailer) Send(mailer.Message) error {
+	return errors.New("mail server down")
+}
+
+func Test_UserSignupMailFailure(t *testing.T) {
+	app := newTestApp(t)
+	app.mailer = failingMailer{}
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	_, _, body := ts.get(t, "/user/signup")
+
+	form := url.Values{}
+	form.Add("name", "User")
+	form.Add("password", "password")
+	form.Add("email", "user@test.com")
+	form.Add("csrf_token", extractCsrfToken(t, body))
+
+	code, header, _ := ts.postForm(t, "/user/signup", form)
+	app.wg.Wait()
+
+	tests.Equal(t, code, http.StatusSeeOther)
+	tests.Equal(t, header.Get("Location"), "/user/login")
+}
+
+func Test_UserVerify(t *testing.T) {
+	app := newTestApp(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	now := time.Now()
+	sign := func(subject string, expires time.Time) string {
+		return url.QueryEscape(app.signer.Sign(verifyPurpose, subject, expires))
+	}
+
+	testCases := []struct {
+		name        string
+		token       string
+		expLocation string
+	}{
+		{
+			name:        "Valid",
+			token:       sign("3:"+mocks.UnverifiedEmail, now.Add(time.Hour)),
+			expLocation: "/user/login",
+		},
+		{
+			name:        "Expired",
+			token:       sign("3:"+mocks.UnverifiedEmail, now.Add(-time.Minute)),
+			expLocation: "/user/verify/resend",
+		},
+		{
+			name:        "Changed email",
+			token:       sign("3:old@test.com", now.Add(time.Hour)),
+			expLocation: "/user/verify/resend",
+		},
+		{
+			name:        "Other purpose",
+			token:       url.QueryEscape(app.signer.Sign("reset", "3:"+mocks.UnverifiedEmail, now.Add(time.Hour))),
+			expLocation: "/user/verify/resend",
+		},
+		{
+			name:        "Garbage",
+			token:       "garbage",
+			expLocation: "/user/verify/resend",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			code, header, _ := ts.get(t, "/user/verify?token="+tt.token)
+
+			tests.Equal(t, code, http.StatusSeeOther)
+			tests.Equal(t, header.Get("Location"), tt.expLocation)
+		})
+	}
+}
+
+func Test_UserVerifyResend(t *testing.T) {
+	app := newTestApp(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	mailer := app.mailer.(*mocks.Mailer)
+
+	_, _, body := ts.get(t, "/user/verify/resend")
+	csrfToken := extractCsrfToken(t, body)
+
+	testCases := []struct {
+		name    string
+		email   string
+		expCode int
+		expSent bool
+	}{
+		{
+			name:    "Verified user",
+			email:   "user@test.com",
+			expCode: http.StatusSeeOther,
+		},
+		{
+			name:    "Unknown user",
+			email:   "nobody@test.com",
+			expCode: http.StatusSeeOther,
+		},
+		{
+			name:    "Invalid email",
+			email:   "nobody@",
+			expCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:    "Unverified user",
+			email:   mocks.UnverifiedEmail,
+			expCode: http.StatusSeeOther,
+			expSent: true,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("email", tt.email)
+			form.Add("csrf_token", csrfToken)
+
+			code, _, _ := ts.postForm(t, "/user/verify/resend", form)
+			tests.Equal(t, code, tt.expCode)
+
+			app.wg.Wait()
+			_, sent := mailer.Last()
+			tests.Equal(t, sent, tt.expSent)
+		})
+	}
+
+	// link from email verifies account
+	msg, _ := mailer.Last()
+	link, err := url.Parse(strings.TrimSpace(strings.Split(msg.Body, "\n")[4]))
+	tests.NilError(t, err)
+
+	code, header, _ := ts.get(t, link.RequestURI())
+	tests.Equal(t, code, http.StatusSeeOther)
+	tests.Equal(t, header.Get("Location"), "/user/login")
+}
+
+func Test_UserLoginUnverified(t *testing.T) {
+	app := newTestApp(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	_, _, body := ts.get(t, "/user/login")
+
+	form := url.Values{}
+	form.Add("email", mocks.UnverifiedEmail)
+	form.Add("password", "password")
+	form.Add("csrf_token", extractCsrfToken(t, body))
+
+	code, _, body := ts.postForm(t, "/user/login", form)
+
+	tests.Equal(t, code, http.StatusUnprocessableEntity)
+	tests.StringContains(t, body, "Please verify your email address first")
+
+	code, _, _ = ts.get(t, "/snippet/create")
+	tests.Equal(t, code, http.StatusSeeOther)
+}
+
+func Test_UserPasswordForgot(t *testing.T) {
+	app := newTestApp(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	mailer := app.mailer.(*mocks.Mailer)
+
+	_, _, body := ts.get(t, "/user/password/forgot")
+	csrfToken := extractCsrfToken(t, body)
+
+	// flash is shown on login page after redirect
+	forgot := func(email string) (int, string, string) {
+		form := url.Values{}
+		form.Add("email", email)
+		form.Add("csrf_token", csrfToken)
+
+		code, header, _ := ts.postForm(t, "/user/password/forgot", form)
+		_, _, page := ts.get(t, "/user/login")
+
+		return code, header.Get("Location"), page
+	}
+
+	unknownCode, unknownLocation, unknownPage := forgot("nobody@test.com")
+	app.wg.Wait()
+
+	_, sent := mailer.Last()
+	tests.Equal(t, sent, false)
+
+	code, location, page := forgot("user@test.com")
+	app.wg.Wait()
+
+	// response doesnt reveal whether account exists
+	tests.Equal(t, code, unknownCode)
+	tests.Equal(t, location, unknownLocation)
+	tests.StringContains(t, unknownPage, "If account with that email exists")
+	tests.StringContains(t, page, "If account with that email exists")
+
+	msg, sent := mailer.Last()
+	tests.Equal(t, sent, true)
+	tests.Equal(t, msg.To, "user@test.com")
+	tests.StringContains(t, msg.Body, "https://localhost:5000/user/password/reset/"+mocks.ValidResetToken)
+
+	code, _, _ = ts.postForm(t, "/user/password/forgot", url.Values{"email": {"nobody@"}, "csrf_token": {csrfToken}})
+	tests.Equal(t, code, http.StatusUnprocessableEntity)
+}
+
+func Test_UserPasswordForgotNotLogged(t *testing.T) {
+	var logs bytes.Buffer
+
+	app := newTestApp(t)
+	app.logger = slog.New(slog.NewJSONHandler(&logs, nil))
+	// default transport outside debug mode
+	app.mailer = newMailer(app.config, app.logger)
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	_, _, body := ts.get(t, "/user/password/forgot")
+
+	form := url.Values{}
+	form.Add("email", "user@test.com")
+	form.Add("csrf_token", extractCsrfToken(t, body))
+
+	ts.postForm(t, "/user/password/forgot", form)
+	app.wg.Wait()
+
+	tests.StringContains(t, logs.String(), `"subject":"Reset your Snippetbox password"`)
+
+	if strings.Contains(logs.String(), mocks.ValidResetToken) {
+		t.Errorf("reset token logged: %s", logs.String())
+	}
+}
+
+func Test_UserPasswordReset(t *testing.T) {
+	app := newTestApp(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	const resetURL = "/user/password/reset/" + mocks.ValidResetToken
+
+	code, header, _ := ts.get(t, "/user/password/reset/unknown")
+	tests.Equal(t, code, http.StatusSeeOther)
+	tests.Equal(t, header.Get("Location"), "/user/password/forgot")
+
+	code, _, body := ts.get(t, resetURL)
+	tests.Equal(t, code, http.StatusOK)
+	tests.StringContains(t, body, "<form action='"+resetURL+"' method='POST' novalidate>")
+
+	csrfToken := extractCsrfToken(t, body)
+
+	testCases := []struct {
+		name         string
+		url          string
+		password     string
+		confirmation string
+		expCode      int
+		expLocation  string
+	}{
+		{
+			name:         "Short password",
+			url:          resetURL,
+			password:     "short",
+			confirmation: "short",
+			expCode:      http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "Passwords dont match",
+			url:          resetURL,
+			password:     "new-password",
+			confirmation: "other-password",
+			expCode:      http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "Invalid token",
+			url:          "/user/password/reset/unknown",
+			password:     "new-password",
+			confirmation: "new-password",
+			expCode:      http.StatusSeeOther,
+			expLocation:  "/user/password/forgot",
+		},
+		{
+			name:         "Valid",
+			url:          resetURL,
+			password:     "new-password",
+			confirmation: "new-password",
+			expCode:      http.StatusSeeOther,
+			expLocation:  "/user/login",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("newPassword", tt.password)
+			form.Add("newPasswordConfirmation", tt.confirmation)
+			form.Add("csrf_token", csrfToken)
+
+			code, header, _ := ts.postForm(t, tt.url, form)
+
+			tests.Equal(t, code, tt.expCode)
+			tests.Equal(t, header.Get("Location"), tt.expLocation)
+		})
+	}
+}
+
+func Test_UserPasswordResetRevokesSessions(t *testing.T) {
+	app := newTestApp(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	// user is logged in on other device
+	other := newTestServer(t, app.routes())
+	defer other.Close()
+
+	other.login(t)
+
+	code, _, _ := other.get(t, "/account/view")
+	tests.Equal(t, code, http.StatusOK)
+
+	_, _, body := ts.get(t, "/user/password/reset/"+mocks.ValidResetToken)
+
+	form := url.Values{}
+	form.Add("newPassword", "new-password")
+	form.Add("newPasswordConfirmation", "new-password")
+	form.Add("csrf_token", extractCsrfToken(t, body))
+
+	code, _, _ = ts.postForm(t, "/user/password/reset/"+mocks.ValidResetToken, form)
+	tests.Equal(t, code, http.StatusSeeOther)
+
+	code, header, _ := other.get(t, "/account/view")
+	tests.Equal(t, code, http.StatusSeeOther)
+	tests.Equal(t, header.Get("Location"), "/user/login")
+}
+
+func Test_UserPasswordResetRevokesTokens(t *testing.T) {
+	app := newTestApp(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	createWithToken := func() int {
+		req, err := http.NewRequest(http.MethodPost, ts.URL+"/api/v1/snippets", strings.NewReader(`{"title":"Title","content":"Content","expires":7}`))
+		tests.NilError(t, err)
+
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+mocks.ValidToken)
+
+		rs, err := ts.Client().Do(req)
+		tests.NilError(t, err)
+		rs.Body.Close()
+
+		return rs.StatusCode
+	}
+
+	tests.Equal(t, createWithToken(), http.StatusCreated)
+
+	_, _, body := ts.get(t, "/user/password/reset/"+mocks.ValidResetToken)
+
+	form := url.Values{}
+	form.Add("newPassword", "new-password")
+	form.Add("newPasswordConfirmation", "new-password")
+	form.Add("csrf_token", extractCsrfToken(t, body))
+
+	code, _, _ := ts.postForm(t, "/user/password/reset/"+mocks.ValidResetToken, form)
+	tests.Equal(t, code, http.StatusSeeOther)
+
+	tests.Equal(t, createWithToken(), http.StatusUnauthorized)
+}
+
+func Test_SnippetEdit(t *testing.T) {
+	app := newTestApp(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	t.Run("Unauthenticated", func(t *testing.T) {
+		status, header, _ := ts.get(t, "/snippet/edit/mockSnippet1")
+
+		tests.Equal(t, status, http.StatusSeeOther)
+		tests.Equal(t, header.Get("Location"), "/user/login")
+	})
+
+	csrfToken := ts.login(t)
+
+	testCases := []struct {
+		name    string
+		slug    string
+		title   string
+		expires string
+		expCode int
+	}{
+		{
+			name:    "Owner",
+			slug:    "mockSnippet1",
+			title:   "New title",
+			expires: "7",
+			expCode: http.StatusSeeOther,
+		},
+		{
+			name:    "Keep expiry",
+			slug:    "mockSnippet1",
+			title:   "New title",
+			expires: "0",
+			expCode: http.StatusSeeOther,
+		},
+		{
+			name:    "Invalid expiry",
+			slug:    "mockSnippet1",
+			title:   "New title",
+			expires: "30",
+			expCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:    "Not owner",
+			slug:    "mockSnippet2",
+			title:   "New title",
+			expires: "7",
+			expCode: http.StatusForbidden,
+		},
+		{
+			name:    "Non-existent ID",
+			slug:    "mockSnippet9",
+			title:   "New title",
+			expires: "7",
+			expCode: http.StatusNotFound,
+		},
+		{
+			name:    "Empty title",
+			slug:    "mockSnippet1",
+			title:   "",
+			expires: "7",
+			expCode: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("title", tt.title)
+			form.Add("content", "Content")
+			form.Add("language", "go")
+			form.Add("visibility", "public")
+			form.Add("expires", tt.expires)
+			form.Add("csrf_token", csrfToken)
+
+			code, _, _ := ts.postForm(t, "/snippet/edit/"+tt.slug, form)
+
+			tests.Equal(t, code, tt.expCode)
+		})
+	}
+
+	t.Run("Edit form", func(t *testing.T) {
+		code, _, body := ts.get(t, "/snippet/edit/mockSnippet1")
+
+		tests.Equal(t, code, http.StatusOK)
+		tests.StringContains(t, body, "<form action='/snippet/edit/mockSnippet1' method='POST'>")
+		tests.StringContains(t, body, "<input type='radio' name='expires' value='0' checked>")
+	})
+
+	t.Run("Expired snippet renews", func(t *testing.T) {
+		code, _, body := ts.get(t, "/snippet/edit/mockSnippet3")
+
+		tests.Equal(t, code, http.StatusOK)
+		tests.StringContains(t, body, "<input type='radio' name='expires' value='365' checked>")
+	})
+}
+
+func Test_SnippetDelete(t *testing.T) {
+	app := newTestApp(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	csrfToken := ts.login(t)
+
+	testCases := []struct {
+		name    string
+		slug    string
+		expCode int
+	}{
+		{
+			name:    "Not owner",
+			slug:    "mockSnippet2",
+			expCode: http.StatusForbidden,
+		},
+		{
+			name:    "Non-existent ID",
+			slug:    "mockSnippet9",
+			expCode: http.StatusNotFound,
+		},
+		{
+			name:    "Owner",
+			slug:    "mockSnippet1",
+			expCode: http.StatusSeeOther,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("csrf_token", csrfToken)
+
+			code, _, _ := ts.postForm(t, "/snippet/delete/"+tt.slug, form)
+
+			tests.Equal(t, code, tt.expCode)
+		})
+	}
+}
+
+func Test_AccountSnippets(t *testing.T) {
+	app := newTestApp(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	t.Run("Unauthenticated", func(t *testing.T) {
+		status, header, _ := ts.get(t, "/account/snippets")
+
+		tests.Equal(t, status, http.StatusSeeOther)
+		tests.Equal(t, header.Get("Location"), "/user/login")
+	})
+
+	ts.login(t)
+
+	testCases := []struct {
+		name    string
+		url     string
+		expBody string
+	}{
+		{
+			name:    "First page",
+			url:     "/account/snippets",
+			expBody: "Snippet Title",
+		},
+		{
+			name:    "Marks expired",
+			url:     "/account/snippets",
+			expBody: "<span class='expired'>expired</span>",
+		},
+		{
+			name:    "Sort by expires",
+			url:     "/account/snippets?sort=expires",
+			expBody: "<strong>Expires</strong>",
+		},
+		{
+			name:    "Invalid sort",
+			url:     "/account/snippets?sort=title",
+			expBody: "<strong>Created</strong>",
+		},
+		{
+			name:    "Page out of range",
+			url:     "/account/snippets?page=2",
+			expBody: "You haven't created any snippets yet.",
+		},
+		{
+			name:    "Huge page",
+			url:     "/account/snippets?page=9223372036854775807",
+			expBody: "You haven't created any snippets yet.",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, tt.url)
+
+			tests.Equal(t, code, http.StatusOK)
+			tests.StringContains(t, body, tt.expBody)
+		})
+	}
+}
+
+func Test_SnippetSearch(t *testing.T) {
+	app := newTestApp(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	testCases := []struct {
+		name       string
+		url        string
+		expBody    string
+		expMissing string
+	}{
+		{
+			name:    "Empty query",
+			url:     "/snippet/search",
+			expBody: "<form action='/snippet/search' method='GET'>",
+		},
+		{
+			name:       "Match by title",
+			url:        "/snippet/search?q=foreign",
+			expBody:    "Foreign Title",
+			expMissing: "Snippet Title",
+		},
+		{
+			name:    "Match by content",
+			url:     "/snippet/search?q=content",
+			expBody: "Snippet Title",
+		},
+		{
+			name:       "Excludes expired",
+			url:        "/snippet/search?q=expired",
+			expBody:    "No snippets found.",
+			expMissing: "Expired Title",
+		},
+		{
+			name:    "Keeps query in pagination",
+			url:     "/snippet/search?q=content&page=2",
+			expBody: "/snippet/search?page=1&amp;q=content",
+		},
+		{
+			name:    "Huge page",
+			url:     "/snippet/search?q=content&page=9223372036854775807",
+			expBody: "No snippets found.",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, tt.url)
+
+			tests.Equal(t, code, http.StatusOK)
+			tests.StringContains(t, body, tt.expBody)
+
+			if tt.expMissing != "" && strings.Contains(body, tt.expMissing) {
+				t.Errorf("body contains %q", tt.expMissing)
+			}
+		})
+	}
+}
+
+func Test_SnippetCreatePost(t *testing.T) {
+	app := newTestApp(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	csrfToken := ts.login(t)
+
+	testCases := []struct {
+		name     string
+		title    string
+		language string
+		expCode  int
+		expBody  string
+	}{
+		{
+			name:     "Valid submission",
+			title:    "Title",
+			language: "go",
+			expCode:  http.StatusSeeOther,
+		},
+		{
+			name:     "Unsupported language",
+			title:    "Title",
+			language: "cobol",
+			expCode:  http.StatusUnprocessableEntity,
+			expBody:  "This language is not supported",
+		},
+		{
+			name:     "Empty title",
+			title:    "",
+			language: "go",
+			expCode:  http.StatusUnprocessableEntity,
+			expBody:  "This field cant be empty",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("title", tt.title)
+			form.Add("content", "Content")
+			form.Add("language", tt.language)
+			form.Add("visibility", "public")
+			form.Add("expires", "7")
+			form.Add("csrf_token", csrfToken)
+
+			code, _, body := ts.postForm(t, "/snippet/create", form)
+
+			tests.Equal(t, code, tt.expCode)
+
+			if tt.expBody != "" {
+				tests.StringContains(t, body, tt.expBody)
+			}
+		})
+	}
+}
+
+func Test_HighlightCSS(t *testing.T) {
+	app := newTestApp(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	code, header, body := ts.get(t, "/static/css/highlight.css")
+
+	tests.Equal(t, code, http.StatusOK)
+	tests.Equal(t, header.Get("Content-Type"), "text/css; charset=utf-8")
+	tests.StringContains(t, body, ".chroma")
+}
+
+func Test_SnippetViewEscapesHTML(t *testing.T) {
+	app := newTestApp(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	code, _, body := ts.get(t, "/snippet/view/mockSnippet4")
+
+	tests.Equal(t, code, http.StatusOK)
+
+	for _, field := range []string{"title", "content", "name"} {
+		tests.StringContains(t, body, "&lt;script&gt;alert(&#39;"+field+"&#39;)&lt;/script&gt;")
+
+		if strings.Contains(body, "<script>alert('"+field+"')</script>") {
+			t.Errorf("got unescaped %s", field)
+		}
+	}
+}
+
+func Test_AccountTokens(t *testing.T) {
+	app := newTestApp(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	csrfToken := ts.login(t)
+
+	t.Run("List", func(t *testing.T) {
+		code, _, body := ts.get(t, "/account/tokens")
+
+		tests.Equal(t, code, http.StatusOK)
+		tests.StringContains(t, body, "<td>CI</td>")
+	})
+
+	t.Run("Create shows token once", func(t *testing.T) {
+		form := url.Values{}
+		form.Add("name", "Deploy")
+		form.Add("csrf_token", csrfToken)
+
+		code, _, body := ts.postForm(t, "/account/tokens", form)
+
+		tests.Equal(t, code, http.StatusCreated)
+		tests.StringContains(t, body, mocks.ValidToken)
+	})
+
+	t.Run("Create with empty name", func(t *testing.T) {
+		form := url.Values{}
+		form.Add("name", "")
+		form.Add("csrf_token", csrfToken)
+
+		code, _, body := ts.postForm(t, "/account/tokens", form)
+
+		tests.Equal(t, code, http.StatusUnprocessableEntity)
+		tests.StringContains(t, body, "This field cant be empty")
+	})
+
+	t.Run("Revoke", func(t *testing.T) {
+		form := url.Values{}
+		form.Add("csrf_token", csrfToken)
+
+		code, header, _ := ts.postForm(t, "/account/tokens/revoke/1", form)
+
+		tests.Equal(t, code, http.StatusSeeOther)
+		tests.Equal(t, header.Get("Location"), "/account/tokens")
+
+		code, _, _ = ts.postForm(t, "/account/tokens/revoke/99", form)
+
+		tests.Equal(t, code, http.StatusNotFound)
+	})
+}
+
+func Test_BearerTokenSkipsCSRF(t *testing.T) {
+	app := newTestApp(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	form := url.Values{}
+	form.Add("title", "Title")
+	form.Add("content", "Content")
+	form.Add("language", "go")
+	form.Add("visibility", "public")
+	form.Add("expires", "7")
+
+	req, err := http.NewRequest(http.MethodPost, ts.URL+"/snippet/create", strings.NewReader(form.Encode()))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "Bearer "+mocks.ValidToken)
+
+	rs, err := ts.Client().Do(req)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer rs.Body.Close()
+
+	tests.Equal(t, rs.StatusCode, http.StatusSeeOther)
+	tests.Equal(t, rs.Header.Get("Location"), "/snippet/view/mockSnippet2")
+}
+
+func Test_SnippetVisibility(t *testing.T) {
+	app := newTestApp(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	testCases := []struct {
+		name    string
+		url     string
+		expCode int
+	}{
+		{
+			name:    "Public",
+			url:     "/snippet/view/mockSnippet2",
+			expCode: http.StatusOK,
+		},
+		{
+			name:    "Unlisted by link",
+			url:     "/snippet/view/mockSnippet6",
+			expCode: http.StatusOK,
+		},
+		{
+			name:    "Private of another user",
+			url:     "/snippet/view/mockSnippet5",
+			expCode: http.StatusNotFound,
+		},
+		{
+			name:    "Private via api",
+			url:     "/api/v1/snippets/mockSnippet5",
+			expCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, _ := ts.get(t, tt.url)
+
+			tests.Equal(t, code, tt.expCode)
+		})
+	}
+
+	t.Run("Hidden from search", func(t *testing.T) {
+		_, _, body := ts.get(t, "/snippet/search?q=title")
+
+		tests.StringContains(t, body, "Foreign Title")
+
+		for _, title := range []string{"Private Title", "Unlisted Title"} {
+			if strings.Contains(body, title) {
+				t.Errorf("search shows %q", title)
+			}
+		}
+	})
+
+	csrfToken := ts.login(t)
+
+	t.Run("Edit private of another user", func(t *testing.T) {
+		code, _, _ := ts.get(t, "/snippet/edit/mockSnippet5")
+
+		tests.Equal(t, code, http.StatusNotFound)
+	})
+
+	t.Run("Invalid visibility", func(t *testing.T) {
+		form := url.Values{}
+		form.Add("title", "Title")
+		form.Add("content", "Content")
+		form.Add("language", "go")
+		form.Add("visibility", "secret")
+		form.Add("expires", "7")
+		form.Add("csrf_token", csrfToken)
+
+		code, _, body := ts.postForm(t, "/snippet/create", form)
+
+		tests.Equal(t, code, http.StatusUnprocessableEntity)
+		tests.StringContains(t, body, "This field must be public, unlisted or private")
+	})
+}
+
+func Test_SnippetHistory(t *testing.T) {
+	app := newTestApp(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	testCases := []struct {
+		name    string
+		url     string
+		expCode int
+		expBody string
+	}{
+		{
+			name:    "History",
+			url:     "/snippet/mockSnippet1/history",
+			expCode: http.StatusOK,
+			expBody: "/snippet/mockSnippet1/diff?from=1&to=2",
+		},
+		{
+			name:    "History of private snippet",
+			url:     "/snippet/mockSnippet5/history",
+			expCode: http.StatusNotFound,
+		},
+		{
+			name:    "Diff",
+			url:     "/snippet/mockSnippet1/diff?from=1&to=2",
+			expCode: http.StatusOK,
+			expBody: "<span class='delete'>-Old Content</span>\n<span class='insert'>&#43;Snippet Content</span>",
+		},
+		{
+			name:    "Diff identical",
+			url:     "/snippet/mockSnippet1/diff?from=2&to=2",
+			expCode: http.StatusOK,
+			expBody: "Content is identical.",
+		},
+		{
+			name:    "Diff missing revision",
+			url:     "/snippet/mockSnippet1/diff?from=1&to=3",
+			expCode: http.StatusNotFound,
+		},
+		{
+			name:    "Diff invalid revision",
+			url:     "/snippet/mockSnippet1/diff?from=one&to=2",
+			expCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, tt.url)
+
+			tests.Equal(t, code, tt.expCode)
+
+			if tt.expBody != "" {
+				tests.StringContains(t, body, tt.expBody)
+			}
+		})
+	}
+}
+
+func Test_SnippetRestore(t *testing.T) {
+	app := newTestApp(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	csrfToken := ts.login(t)
+
+	testCases := []struct {
+		name    string
+		url     string
+		expCode int
+	}{
+		{
+			name:    "Owner",
+			url:     "/snippet/mockSnippet1/restore/1",
+			expCode: http.StatusSeeOther,
+		},
+		{
+			name:    "Missing revision",
+			url:     "/snippet/mockSnippet1/restore/7",
+			expCode: http.StatusNotFound,
+		},
+		{
+			name:    "Not owner",
+			url:     "/snippet/mockSnippet2/restore/1",
+			expCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("csrf_token", csrfToken)
+
+			code, _, _ := ts.postForm(t, tt.url, form)
+
+			tests.Equal(t, code, tt.expCode)
+		})
+	}
+}
+
+func Test_SnippetFork(t *testing.T) {
+	app := newTestApp(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	t.Run("Unauthenticated", func(t *testing.T) {
+		code, _, body := ts.get(t, "/snippet/view/mockSnippet2")
+
+		tests.Equal(t, code, http.StatusOK)
+
+		if strings.Contains(body, "?fork=") {
+			t.Errorf("fork action shown to anonymous user")
+		}
+	})
+
+	csrfToken := ts.login(t)
+
+	getCases := []struct {
+		name    string
+		url     string
+		expCode int
+		expBody string
+	}{
+		{
+			name:    "Fork action",
+			url:     "/snippet/view/mockSnippet2",
+			expCode: http.StatusOK,
+			expBody: "<a href='/snippet/create?fork=mockSnippet2'>Fork</a>",
+		},
+		{
+			name:    "Prefilled form",
+			url:     "/snippet/create?fork=mockSnippet2",
+			expCode: http.StatusOK,
+			expBody: "<textarea name='content'>Foreign Content</textarea>",
+		},
+		{
+			name:    "Source reference",
+			url:     "/snippet/create?fork=mockSnippet2",
+			expCode: http.StatusOK,
+			expBody: "<input type='hidden' name='fork' value='mockSnippet2'>",
+		},
+		{
+			name:    "Private source",
+			url:     "/snippet/create?fork=mockSnippet5",
+			expCode: http.StatusNotFound,
+		},
+		{
+			name:    "Missing source",
+			url:     "/snippet/create?fork=mockSnippet9",
+			expCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range getCases {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, tt.url)
+
+			tests.Equal(t, code, tt.expCode)
+
+			if tt.expBody != "" {
+				tests.StringContains(t, body, tt.expBody)
+			}
+		})
+	}
+
+	postCases := []struct {
+		name    string
+		fork    string
+		expCode int
+		expBody string
+	}{
+		{
+			name:    "Fork",
+			fork:    "mockSnippet2",
+			expCode: http.StatusSeeOther,
+		},
+		{
+			name:    "Private source",
+			fork:    "mockSnippet5",
+			expCode: http.StatusUnprocessableEntity,
+			expBody: "Snippet you are forking no longer exists",
+		},
+	}
+
+	for _, tt := range postCases {
+		t.Run("Post "+tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("title", "Forked Title")
+			form.Add("content", "Forked Content")
+			form.Add("language", "plaintext")
+			form.Add("visibility", "public")
+			form.Add("expires", "7")
+			form.Add("fork", tt.fork)
+			form.Add("csrf_token", csrfToken)
+
+			code, _, body := ts.postForm(t, "/snippet/create", form)
+
+			tests.Equal(t, code, tt.expCode)
+
+			if tt.expBody != "" {
+				tests.StringContains(t, body, tt.expBody)
+			}
+		})
+	}
+}
+
+func Test_SnippetRaw(t *testing.T) {
+	app := newTestApp(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	testCases := []struct {
+		name           string
+		url            string
+		expCode        int
+		expBody        string
+		expDisposition string
+	}{
+		{
+			name:    "Raw",
+			url:     "/snippet/raw/mockSnippet1",
+			expCode: http.StatusOK,
+			expBody: "Snippet Content",
+		},
+		{
+			name:           "Download",
+			url:            "/snippet/download/mockSnippet1",
+			expCode:        http.StatusOK,
+			expBody:        "Snippet Content",
+			expDisposition: "attachment; filename=snippet-title.txt",
+		},
+		{
+			name:    "Expired",
+			url:     "/snippet/raw/mockSnippet3",
+			expCode: http.StatusNotFound,
+		},
+		{
+			name:    "Private of another user",
+			url:     "/snippet/download/mockSnippet5",
+			expCode: http.StatusNotFound,
+		},
+		{
+			name:    "Missing",
+			url:     "/snippet/raw/mockSnippet9",
+			expCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			code, header, body := ts.get(t, tt.url)
+
+			tests.Equal(t, code, tt.expCode)
+
+			if tt.expCode != http.StatusOK {
+				return
+			}
+
+			tests.Equal(t, body, tt.expBody)
+			tests.Equal(t, header.Get("Content-Type"), "text/plain; charset=utf-8")
+			tests.Equal(t, header.Get("Content-Disposition"), tt.expDisposition)
+		})
+	}
+
+	t.Run("Conditional", func(t *testing.T) {
+		_, header, _ := ts.get(t, "/snippet/raw/mockSnippet1")
+
+		conditions := map[string]string{
+			"If-None-Match":     header.Get("ETag"),
+			"If-Modified-Since": header.Get("Last-Modified"),
+		}
+
+		for name, value := range conditions {
+			req, err := http.NewRequest(http.MethodGet, ts.URL+"/snippet/raw/mockSnippet1", nil)
+			tests.NilError(t, err)
+
+			req.Header.Set(name, value)
+
+			rs, err := ts.Client().Do(req)
+			tests.NilError(t, err)
+			rs.Body.Close()
+
+			tests.Equal(t, rs.StatusCode, http.StatusNotModified)
+		}
+	})
+}
+
+func Test_downloadFilename(t *testing.T) {
+	testCases := []struct {
+		title    string
+		language string
+		want     string
+	}{
+		{title: "Hello World", language: "go", want: "hello-world.go"},
+		{title: "  --Mixed__Case!! ", language: "bash", want: "mixed-case.sh"},
+		{title: "Привет", language: "plaintext", want: "snippet.txt"},
+		{title: "../../etc/passwd", language: "plaintext", want: "etc-passwd.txt"},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.title, func(t *testing.T) {
+			snippet := &models.Snippet{Title: tt.title, Language: tt.language}
+
+			tests.Equal(t, downloadFilename(snippet), tt.want)
+		})
+	}
+}
+
+func Test_Health(t *testing.T) {
+	testCases := []struct {
+		name      string
+		url       string
+		dbErr     error
+		noTmpl    bool
+		draining  bool
+		expCode   int
+		expBodies []string
+		// logged, but kept out of response
+		expLog string
+	}{
+		{
+			name:      "Alive",
+			url:       "/healthz",
+			dbErr:     errors.New("connection refused"),
+			expCode:   http.StatusOK,
+			expBodies: []string{`{"status":"ok"}`},
+		},
+		{
+			name:      "Ready",
+			url:       "/readyz",
+			expCode:   http.StatusOK,
+			expBodies: []string{`"status":"ok","checks":`, `"database":{"status":"ok"`, `"sessions":{"status":"ok"`},
+		},
+		{
+			name:      "Database down",
+			url:       "/readyz",
+			dbErr:     errors.New("connection refused"),
+			expCode:   http.StatusServiceUnavailable,
+			expBodies: []string{`"status":"fail"`, `"database":{"status":"fail"`},
+			expLog:    "connection refused",
+		},
+		{
+			name:      "No templates",
+			url:       "/readyz",
+			noTmpl:    true,
+			expCode:   http.StatusServiceUnavailable,
+			expBodies: []string{`"templates":{"status":"fail"`},
+			expLog:    "no templates loaded",
+		},
+		{
+			name:      "Draining",
+			url:       "/readyz",
+			draining:  true,
+			expCode:   http.StatusServiceUnavailable,
+			expBodies: []string{`"server":{"status":"fail"`},
+			expLog:    "shutting down",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+
+			app := newTestApp(t)
+			app.logger = slog.New(slog.NewTextHandler(&logs, nil))
+			app.db = &mocks.DB{Err: tt.dbErr}
+			app.draining.Store(tt.draining)
+
+			if tt.noTmpl {
+				app.templateCache = nil
+			}
+
+			ts := newTestServer(t, app.routes())
+			defer ts.Close()
+
+			code, header, body := ts.get(t, tt.url)
+
+			tests.Equal(t, code, tt.expCode)
+			tests.Equal(t, header.Get("Content-Type"), "application/json")
+
+			for _, exp := range tt.expBodies {
+				tests.StringContains(t, body, exp)
+			}
+
+			if tt.expLog != "" {
+				tests.StringContains(t, logs.String(), tt.expLog)
+
+				if strings.Contains(body, tt.expLog) || strings.Contains(body, `"error"`) {
+					t.Errorf("body exposes error: %s", body)
+				}
+			}
+		})
+	}
 }
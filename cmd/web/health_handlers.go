@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/alexedwards/scs/v2"
+)
+
+// how long readiness waits for each dependency
+const readyTimeout = 2 * time.Second
+
+// pinger is part of *sql.DB used by readiness check
+type pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+const (
+	checkOK   = "ok"
+	checkFail = "fail"
+)
+
+type checkResult struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+}
+
+type readyBody struct {
+	Status string                  `json:"status"`
+	Checks map[string]*checkResult `json:"checks"`
+}
+
+// healthz reports that process is alive, dependencies arent checked
+// so restart isnt triggered when only database is down
+func (app *App) healthz(w http.ResponseWriter, r *http.Request) {
+	app.writeJSON(w, http.StatusOK, map[string]string{"status": checkOK})
+}
+
+// readyz reports whether app can serve traffic. Response is public,
+// so errors of failed checks are only logged.
+func (app *App) readyz(w http.ResponseWriter, r *http.Request) {
+	checks := map[string]func(ctx context.Context) error{
+		"database":  app.db.PingContext,
+		"sessions":  app.checkSessionStore,
+		"templates": app.checkTemplates,
+		"server":    app.checkDraining,
+	}
+
+	body := readyBody{Status: checkOK, Checks: map[string]*checkResult{}}
+	status := http.StatusOK
+
+	for name, check := range checks {
+		result, err := runCheck(r.Context(), check)
+		body.Checks[name] = result
+
+		if err != nil {
+			app.logger.Warn("readiness check failed", "check", name, "error", err.Error())
+			body.Status = checkFail
+			status = http.StatusServiceUnavailable
+		}
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	app.writeJSON(w, status, body)
+}
+
+// runCheck calls check with timeout, check is abandoned
+// if it ignores context and doesnt return in time
+func runCheck(ctx context.Context, check func(ctx context.Context) error) (*checkResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, readyTimeout)
+	defer cancel()
+
+	start := time.Now()
+	errs := make(chan error, 1)
+
+	go func() {
+		errs <- check(ctx)
+	}()
+
+	var err error
+
+	select {
+	case err = <-errs:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := &checkResult{
+		Status:    checkOK,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+
+	if err != nil {
+		result.Status = checkFail
+	}
+
+	return result, err
+}
+
+// checkSessionStore looks up token which doesnt exist,
+// store returns error only when its backend is unavailable
+func (app *App) checkSessionStore(ctx context.Context) error {
+	const token = "readiness-check"
+
+	var err error
+
+	if store, ok := app.sessionManager.Store.(scs.CtxStore); ok {
+		_, _, err = store.FindCtx(ctx, token)
+	} else {
+		_, _, err = app.sessionManager.Store.Find(token)
+	}
+
+	return err
+}
+
+func (app *App) checkTemplates(ctx context.Context) error {
+	if len(app.templateCache) == 0 {
+		return errors.New("no templates loaded")
+	}
+
+	return nil
+}
+
+func (app *App) checkDraining(ctx context.Context) error {
+	if app.draining.Load() {
+		return errors.New("shutting down")
+	}
+
+	return nil
+}
@@ -7,18 +7,17 @@ import (
 	"net/http"
 	"runtime/debug"
 	"snippetbox/internal/templates"
+	"strconv"
 	"time"
 
 	"github.com/go-playground/form/v4"
 	"github.com/justinas/nosurf"
 )
 
-func (app *App) serverError(w http.ResponseWriter, error error) {
-	// get stack trace for current goroutine
-	trace := fmt.Sprintf("%s\n%s", error.Error(), debug.Stack())
-	app.errLogger.Print(2, trace)
+func (app *App) serverError(w http.ResponseWriter, r *http.Request, err error) {
+	trace := app.logServerError(r, err)
 
-	if app.debug {
+	if app.config.Debug {
 		http.Error(w, trace, http.StatusInternalServerError)
 		return
 	}
@@ -26,6 +25,42 @@ func (app *App) serverError(w http.ResponseWriter, error error) {
 	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 }
 
+// logServerError logs err with stack trace of current goroutine and
+// request id, so it can be matched with request log. Returns trace.
+func (app *App) logServerError(r *http.Request, err error) string {
+	stack := string(debug.Stack())
+
+	app.logger.Error(err.Error(),
+		"request_id", requestID(r),
+		"method", r.Method,
+		"uri", loggedURI(r),
+		"trace", stack,
+	)
+
+	return fmt.Sprintf("%s\n%s", err.Error(), stack)
+}
+
+// background runs fn after handler returns, so slow work like sending
+// email doesnt delay response. Errors and panics are logged.
+func (app *App) background(fn func() error) {
+	app.wg.Add(1)
+
+	go func() {
+		defer app.wg.Done()
+
+		defer func() {
+			if err := recover(); err != nil {
+				app.metrics.panics.Inc()
+				app.logger.Error(fmt.Sprintf("%s", err), "trace", string(debug.Stack()))
+			}
+		}()
+
+		if err := fn(); err != nil {
+			app.logger.Error(err.Error())
+		}
+	}()
+}
+
 func (app *App) clientError(w http.ResponseWriter, status int) {
 	http.Error(w, http.StatusText(status), status)
 }
@@ -34,11 +69,11 @@ func (app *App) notFound(w http.ResponseWriter) {
 	app.clientError(w, http.StatusNotFound)
 }
 
-func (app *App) render(w http.ResponseWriter, status int, page string, data *templates.TemplateData) {
+func (app *App) render(w http.ResponseWriter, r *http.Request, status int, page string, data *templates.TemplateData) {
 	ts, ok := app.templateCache[page]
 
 	if !ok {
-		app.serverError(w, fmt.Errorf("template %s doesnt exist", page))
+		app.serverError(w, r, fmt.Errorf("template %s doesnt exist", page))
 		return
 	}
 
@@ -47,7 +82,7 @@ func (app *App) render(w http.ResponseWriter, status int, page string, data *tem
 	err := ts.ExecuteTemplate(buf, "base", data)
 
 	if err != nil {
-		app.serverError(w, err)
+		app.serverError(w, r, err)
 		return
 	}
 
@@ -57,10 +92,11 @@ func (app *App) render(w http.ResponseWriter, status int, page string, data *tem
 
 func (app *App) newTemplateData(r *http.Request) *templates.TemplateData {
 	return &templates.TemplateData{
-		CurrentYear:     time.Now().Year(),
-		Flash:           app.sessionManager.PopString(r.Context(), "flash"),
-		IsAuthenticated: app.isAuthenticated(r),
-		CSRFToken:       nosurf.Token(r),
+		CurrentYear:         time.Now().Year(),
+		Flash:               app.sessionManager.PopString(r.Context(), "flash"),
+		IsAuthenticated:     app.isAuthenticated(r),
+		AuthenticatedUserID: app.authenticatedUserID(r),
+		CSRFToken:           nosurf.Token(r),
 	}
 }
 
@@ -93,3 +129,63 @@ func (app *App) isAuthenticated(r *http.Request) bool {
 
 	return isAuthenticated
 }
+
+// isTokenAuthenticated reports whether request was authenticated
+// with personal access token instead of session
+func isTokenAuthenticated(r *http.Request) bool {
+	isTokenAuthenticated, ok := r.Context().Value(isTokenAuthenticatedContextKey).(bool)
+
+	if !ok {
+		return false
+	}
+
+	return isTokenAuthenticated
+}
+
+// requestID returns id assigned to request by tagRequest middleware
+func requestID(r *http.Request) string {
+	info, ok := r.Context().Value(requestInfoContextKey).(*requestInfo)
+
+	if !ok {
+		return ""
+	}
+
+	return info.id
+}
+
+// setRequestUserID records authenticated user for request log
+func setRequestUserID(r *http.Request, id int) {
+	if info, ok := r.Context().Value(requestInfoContextKey).(*requestInfo); ok {
+		info.userID = id
+	}
+}
+
+// authenticatedUserID returns id of user authenticated by session or token, 0 if none
+func (app *App) authenticatedUserID(r *http.Request) int {
+	id, ok := r.Context().Value(authenticatedUserIDContextKey).(int)
+
+	if !ok {
+		return 0
+	}
+
+	return id
+}
+
+// queryInt reads positive int from url query, returns def if missing or invalid
+func queryInt(r *http.Request, key string, def int) int {
+	value, err := strconv.Atoi(r.URL.Query().Get(key))
+
+	if err != nil || value < 1 {
+		return def
+	}
+
+	return value
+}
+
+// maxPage bounds page from query, so offset of huge page cant overflow
+const maxPage = 100_000
+
+// queryPage reads page number from url query, 1 if missing or invalid
+func queryPage(r *http.Request) int {
+	return min(queryInt(r, "page", 1), maxPage)
+}
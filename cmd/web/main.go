@@ -1,16 +1,26 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"database/sql"
+	"errors"
 	"flag"
-	"log"
+	"fmt"
+	"html/template"
+	"log/slog"
 	"net/http"
+	"net/netip"
 	"os"
+	"os/signal"
+	"snippetbox/internal/encrypt"
+	"snippetbox/internal/mailer"
 	"snippetbox/internal/models"
+	"snippetbox/internal/signer"
 	"snippetbox/internal/templates"
-	"text/template"
-	"time"
+	"sync"
+	"sync/atomic"
+	"syscall"
 
 	"github.com/alexedwards/scs/mysqlstore"
 	"github.com/alexedwards/scs/v2"
@@ -19,59 +29,154 @@ import (
 )
 
 type App struct {
-	debug          bool
-	errLogger      *log.Logger
-	infoLogger     *log.Logger
+	config         *config
+	logger         *slog.Logger
+	db             pinger
+	metrics        *metrics
 	snippets       models.SnippetRepo
 	users          models.UserRepo
+	tokens         models.TokenRepo
+	resets         models.PasswordResetRepo
+	twoFactor      models.TwoFactorRepo
+	throttle       *loginThrottle
+	trustedProxies []netip.Prefix
+	metricsAllow   []netip.Prefix
 	templateCache  map[string]*template.Template
 	formDecoder    *form.Decoder
 	sessionManager *scs.SessionManager
-}
-
-var flags struct {
-	addr  string
-	dbDsn string
+	mailer         mailer.Mailer
+	signer         *signer.Signer
+	// set when shutdown begins, readiness fails from then on
+	draining atomic.Bool
+	// tracks goroutines started by background
+	wg sync.WaitGroup
 }
 
 func main() {
-	flag.StringVar(&flags.addr, "addr", ":5000", "HTTP network address")
-	flag.StringVar(&flags.dbDsn, "dsn", "root:password@/snippetbox?parseTime=true", "MySQL connect name")
-	debug := flag.Bool("debug", false, "Debug mode")
-	flag.Parse()
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		err := runMigrate(os.Args[2:], os.Stdout)
+
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
 
-	infoLogger := log.New(os.Stdout, "INFO\t", log.Ldate|log.Ltime)
-	errLogger := log.New(os.Stderr, "ERROR\t", log.Ldate|log.Ltime|log.Lshortfile)
+		return
+	}
+
+	cfg, printConfig, err := loadConfig(os.Args[1:], os.LookupEnv)
 
-	db, err := openDB(flags.dbDsn)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
 
 	if err != nil {
-		errLogger.Fatal(err)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	err = cfg.validate()
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid config:\n%s\n", err)
+		os.Exit(2)
+	}
+
+	if printConfig {
+		out, err := cfg.redactedYAML()
+
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		os.Stdout.Write(out)
+		return
 	}
 
-	defer db.Close()
+	logLevel := slog.LevelInfo
+
+	if cfg.Debug {
+		logLevel = slog.LevelDebug
+	}
+
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: logLevel}))
+
+	db, err := openDB(cfg.DSN)
+
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
 
 	templateCache, err := templates.NewTemplateCache()
 
 	if err != nil {
-		errLogger.Fatal(err)
+		logger.Error(err.Error())
+		os.Exit(1)
 	}
 
 	formDecoder := form.NewDecoder()
 	sessionManager := scs.New()
-	sessionManager.Store = mysqlstore.New(db)
-	sessionManager.Lifetime = 12 * time.Hour
+	sessionStore := mysqlstore.New(db)
+	sessionManager.Store = sessionStore
+	sessionManager.Lifetime = cfg.Session.Lifetime
 	sessionManager.Cookie.Secure = true
 
-	app := App{
-		errLogger:      errLogger,
-		infoLogger:     infoLogger,
+	metrics := newMetrics()
+	metrics.registerDB(db)
+
+	// keys are checked by validate
+	sgn, err := signer.New(cfg.SecretKey)
+
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
+	box, err := encrypt.New(cfg.EncryptionKey)
+
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
+	trustedProxies, err := parseTrustedProxies(cfg.TrustedProxies)
+
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
+	metricsAllow, err := parseTrustedProxies(cfg.MetricsAllow)
+
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
+	app := &App{
+		config:         cfg,
+		logger:         logger,
+		db:             db,
+		metrics:        metrics,
 		snippets:       &models.SnippetModel{DB: db},
-		users:          &models.UserModel{DB: db},
+		users:          &models.UserModel{DB: db, BcryptCost: cfg.BcryptCost},
+		tokens:         &models.TokenModel{DB: db},
+		resets:         &models.PasswordResetModel{DB: db},
+		twoFactor:      &models.TwoFactorModel{DB: db, Box: box},
+		throttle:       newLoginThrottle(cfg, &models.LoginAttemptModel{DB: db}, logger),
+		trustedProxies: trustedProxies,
+		metricsAllow:   metricsAllow,
 		templateCache:  templateCache,
 		formDecoder:    formDecoder,
 		sessionManager: sessionManager,
-		debug:          *debug,
+		mailer:         newMailer(cfg, logger),
+		signer:         sgn,
 	}
 
 	tlsConfig := &tls.Config{
@@ -81,18 +186,69 @@ func main() {
 
 	// custom config for server
 	srv := &http.Server{
-		Addr:         flags.addr,
-		ErrorLog:     errLogger,
+		Addr:         cfg.Addr,
+		ErrorLog:     slog.NewLogLogger(logger.Handler(), slog.LevelError),
 		Handler:      app.routes(),
 		TLSConfig:    tlsConfig,
-		IdleTimeout:  time.Minute,
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 10 * time.Second,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var reaperDone <-chan struct{}
+
+	if cfg.Reaper.Interval > 0 {
+		reaperDone = newReaper(cfg, app.snippets, logger).start(ctx)
+	}
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+
+	listen := func() error {
+		return srv.ListenAndServeTLS(cfg.TLS.Cert, cfg.TLS.Key)
+	}
+
+	logger.Info("started listening", "addr", cfg.Addr)
+	err = app.serve(srv, listen, quit, cfg.Server.DrainDelay, cfg.Server.ShutdownTimeout)
+
+	// background workers may still use db, so they are stopped first
+	cancel()
+	app.wg.Wait()
+	sessionStore.StopCleanup()
+
+	if reaperDone != nil {
+		<-reaperDone
+	}
+
+	if closeErr := db.Close(); closeErr != nil {
+		logger.Error(closeErr.Error())
+	}
+
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
 	}
 
-	infoLogger.Printf("Started listening on: %s", flags.addr)
-	err = srv.ListenAndServeTLS("./tls/cert.pem", "./tls/key.pem")
-	errLogger.Fatal(err)
+	logger.Info("stopped")
+}
+
+func newMailer(cfg *config, logger *slog.Logger) mailer.Mailer {
+	switch cfg.Mail.Transport {
+	case "smtp":
+		return &mailer.SMTP{
+			Host:     cfg.Mail.SMTP.Host,
+			Port:     cfg.Mail.SMTP.Port,
+			Username: cfg.Mail.SMTP.Username,
+			Password: cfg.Mail.SMTP.Password,
+			From:     cfg.Mail.From,
+		}
+	case "file":
+		return &mailer.File{Dir: cfg.Mail.Dir, From: cfg.Mail.From}
+	default:
+		// links in bodies are only needed when developing
+		return &mailer.Log{Logger: logger, Body: cfg.Debug}
+	}
 }
 
 func openDB(dsn string) (*sql.DB, error) {
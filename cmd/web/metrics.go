@@ -0,0 +1,170 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metrics are kept in own registry instead of global one,
+// so every App (and test) gets fresh set of collectors
+type metrics struct {
+	registry        *prometheus.Registry
+	requests        *prometheus.CounterVec
+	duration        *prometheus.HistogramVec
+	inFlight        prometheus.Gauge
+	snippetsCreated prometheus.Counter
+	logins          *prometheus.CounterVec
+	rateLimited     *prometheus.CounterVec
+	panics          prometheus.Counter
+}
+
+// login results
+const (
+	loginSuccess = "success"
+	loginFailure = "failure"
+	// rejected by throttle without checking password
+	loginThrottled = "throttled"
+)
+
+func newMetrics() *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Number of HTTP requests by route pattern and status.",
+		}, []string{"method", "route", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "HTTP request latency by route pattern and status.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "http_requests_in_flight",
+			Help: "Number of HTTP requests being served.",
+		}),
+		snippetsCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snippetbox_snippets_created_total",
+			Help: "Number of created snippets.",
+		}),
+		logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "snippetbox_logins_total",
+			Help: "Number of login attempts by result.",
+		}, []string{"result"}),
+		rateLimited: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "snippetbox_rate_limited_total",
+			Help: "Number of requests rejected by rate limit by route group.",
+		}, []string{"group"}),
+		panics: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snippetbox_panics_total",
+			Help: "Number of panics recovered in handlers.",
+		}),
+	}
+
+	m.registry.MustRegister(
+		m.requests,
+		m.duration,
+		m.inFlight,
+		m.snippetsCreated,
+		m.logins,
+		m.rateLimited,
+		m.panics,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	// results exist from start, so rate() works before first login
+	m.logins.WithLabelValues(loginSuccess)
+	m.logins.WithLabelValues(loginFailure)
+	m.logins.WithLabelValues(loginThrottled)
+
+	return m
+}
+
+// registerDB exposes connection pool statistics of db
+func (m *metrics) registerDB(db *sql.DB) {
+	m.registry.MustRegister(collectors.NewDBStatsCollector(db, "snippetbox"))
+}
+
+func (m *metrics) handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// allowIPs rejects clients outside of allowed IPs and CIDRs.
+// Must run after realIP, so client behind trusted proxy is checked.
+func (app *App) allowIPs(allowed []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			addr, err := netip.ParseAddr(clientIP(r))
+
+			if err == nil {
+				addr = addr.Unmap()
+
+				for _, prefix := range allowed {
+					if prefix.Contains(addr) {
+						next.ServeHTTP(w, r)
+						return
+					}
+				}
+			}
+
+			app.clientError(w, http.StatusForbidden)
+		})
+	}
+}
+
+// methodLabel returns method if it is standard one, otherwise "other".
+// Method is chosen by client, so it cant be used as label directly.
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	default:
+		return "other"
+	}
+}
+
+// collectMetrics records count and latency of requests.
+// Route pattern is used instead of path so slugs dont blow up label cardinality.
+func (app *App) collectMetrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		app.metrics.inFlight.Inc()
+		defer app.metrics.inFlight.Dec()
+
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		route := chi.RouteContext(r.Context()).RoutePattern()
+
+		if route == "" {
+			route = "unmatched"
+		}
+
+		status := ww.Status()
+
+		// handler wrote nothing
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		labels := prometheus.Labels{
+			"method": methodLabel(r.Method),
+			"route":  route,
+			"status": strconv.Itoa(status),
+		}
+
+		app.metrics.requests.With(labels).Inc()
+		app.metrics.duration.With(labels).Observe(time.Since(start).Seconds())
+	})
+}
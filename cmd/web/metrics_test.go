@@ -0,0 +1,119 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"snippetbox/internal/tests"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func Test_Metrics(t *testing.T) {
+	app := newTestApp(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.get(t, "/snippet/view/mockSnippet1")
+	ts.get(t, "/snippet/view/mockSnippet2")
+	ts.get(t, "/no/such/page")
+
+	req, err := http.NewRequest("MADEUP", ts.URL+"/no/such/page", nil)
+	tests.NilError(t, err)
+
+	rs, err := ts.Client().Do(req)
+	tests.NilError(t, err)
+	rs.Body.Close()
+
+	_, _, body := ts.get(t, "/user/login")
+
+	form := url.Values{}
+	form.Add("email", "user@test.com")
+	form.Add("password", "wrongpassword")
+	form.Add("csrf_token", extractCsrfToken(t, body))
+
+	ts.postForm(t, "/user/login", form)
+
+	code, _, body := ts.get(t, "/metrics")
+
+	tests.Equal(t, code, http.StatusOK)
+
+	expected := []string{
+		`http_requests_total{method="GET",route="/snippet/view/{slug}",status="200"} 2`,
+		`http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`http_requests_total{method="other",route="unmatched",status="405"} 1`,
+		`http_request_duration_seconds_count{method="GET",route="/snippet/view/{slug}",status="200"} 2`,
+		`http_requests_in_flight 1`,
+		`snippetbox_logins_total{result="failure"} 1`,
+		`snippetbox_logins_total{result="success"} 0`,
+		`snippetbox_snippets_created_total 0`,
+		`snippetbox_panics_total 0`,
+	}
+
+	for _, exp := range expected {
+		tests.StringContains(t, body, exp)
+	}
+}
+
+func Test_MetricsForbidden(t *testing.T) {
+	trusted, err := parseTrustedProxies([]string{"127.0.0.1"})
+	tests.NilError(t, err)
+
+	testCases := []struct {
+		name      string
+		allow     []string
+		forwarded string
+		expCode   int
+	}{
+		{name: "Allowed", allow: []string{"127.0.0.0/8"}, expCode: http.StatusOK},
+		{name: "Not allowed", allow: []string{"10.0.0.1"}, expCode: http.StatusForbidden},
+		{name: "Empty list", expCode: http.StatusForbidden},
+		{name: "Behind proxy", allow: []string{"10.0.0.1"}, forwarded: "10.0.0.1", expCode: http.StatusOK},
+		{name: "Proxy itself", allow: []string{"127.0.0.1"}, forwarded: "203.0.113.9", expCode: http.StatusForbidden},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApp(t)
+			app.trustedProxies = trusted
+			app.metricsAllow, err = parseTrustedProxies(tt.allow)
+			tests.NilError(t, err)
+
+			ts := newTestServer(t, app.routes())
+			defer ts.Close()
+
+			req, err := http.NewRequest(http.MethodGet, ts.URL+"/metrics", nil)
+			tests.NilError(t, err)
+
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+
+			rs, err := ts.Client().Do(req)
+			tests.NilError(t, err)
+			rs.Body.Close()
+
+			tests.Equal(t, rs.StatusCode, tt.expCode)
+		})
+	}
+}
+
+func Test_recoverPanicMetrics(t *testing.T) {
+	app := newTestApp(t)
+
+	router := chi.NewRouter()
+	router.Use(app.collectMetrics, app.recoverPanic)
+	router.Get("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	ts := newTestServer(t, router)
+	defer ts.Close()
+
+	code, _, _ := ts.get(t, "/panic")
+
+	tests.Equal(t, code, http.StatusInternalServerError)
+	tests.Equal(t, testutil.ToFloat64(app.metrics.panics), float64(1))
+	tests.Equal(t, testutil.ToFloat64(app.metrics.requests.WithLabelValues("GET", "/panic", "500")), float64(1))
+}
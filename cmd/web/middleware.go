@@ -2,9 +2,18 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"net/http"
+	"regexp"
+	"snippetbox/internal/models"
+	"strings"
+	"time"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/justinas/nosurf"
 )
 
@@ -22,10 +31,94 @@ func headerMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// longest X-Request-ID accepted from client
+const maxRequestIDLength = 64
+
+var requestIDRegex = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+
+// tagRequest assigns id to request, id set by proxy in X-Request-ID
+// header is kept. Id is sent back in same header.
+func tagRequest(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+
+		// id ends up in logs, so arbitrary client input isnt accepted
+		if len(id) > maxRequestIDLength || !requestIDRegex.MatchString(id) {
+			id = newRequestID()
+		}
+
+		w.Header().Set("X-Request-ID", id)
+
+		ctx := context.WithValue(r.Context(), requestInfoContextKey, &requestInfo{id: id})
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+
+	// crypto/rand doesnt fail on supported platforms
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+// path of password reset links, rest of path is token
+const resetPathPrefix = "/user/password/reset/"
+
+// loggedURI returns request URI with tokens from email links redacted,
+// so logs cant be used to take over accounts
+func loggedURI(r *http.Request) string {
+	u := *r.URL
+
+	if strings.HasPrefix(u.Path, resetPathPrefix) {
+		u.Path = resetPathPrefix + redacted
+		u.RawPath = ""
+	}
+
+	query := u.Query()
+
+	if query.Has("token") {
+		query.Set("token", redacted)
+		u.RawQuery = query.Encode()
+	}
+
+	return u.RequestURI()
+}
+
+// logRequests logs request after response is written
 func (app *App) logRequests(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		app.infoLogger.Printf("%s - %s %s %s", r.RemoteAddr, r.Proto, r.Method, r.URL.RequestURI())
-		next.ServeHTTP(w, r)
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		status := ww.Status()
+
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		userID := 0
+
+		if info, ok := r.Context().Value(requestInfoContextKey).(*requestInfo); ok {
+			userID = info.userID
+		}
+
+		app.logger.Info("request",
+			"request_id", requestID(r),
+			"remote_addr", r.RemoteAddr,
+			"proto", r.Proto,
+			"method", r.Method,
+			"uri", loggedURI(r),
+			"route", chi.RouteContext(r.Context()).RoutePattern(),
+			"status", status,
+			"bytes", ww.BytesWritten(),
+			"duration", time.Since(start),
+			"user_id", userID,
+		)
 	})
 }
 
@@ -33,8 +126,9 @@ func (app *App) recoverPanic(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if err := recover(); err != nil {
+				app.metrics.panics.Inc()
 				w.Header().Set("Connection", "close")
-				app.serverError(w, fmt.Errorf("%s", err))
+				app.serverError(w, r, fmt.Errorf("%s", err))
 			}
 		}()
 
@@ -55,9 +149,26 @@ func (app *App) requireAuth(next http.Handler) http.Handler {
 	})
 }
 
+// requireAPIAuth is requireAuth for api routes,
+// responds with json error instead of redirect to login page
+func (app *App) requireAPIAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !app.isAuthenticated(r) {
+			app.apiClientError(w, http.StatusUnauthorized)
+			return
+		}
+
+		w.Header().Add("Cache-control", "no-store")
+		next.ServeHTTP(w, r)
+	})
+}
+
 func noSurf(next http.Handler) http.Handler {
 	csrfHandler := nosurf.New(next)
 
+	// bearer token isnt sent by browser automatically so CSRF isnt possible
+	csrfHandler.ExemptFunc(isTokenAuthenticated)
+
 	csrfHandler.SetBaseCookie(http.Cookie{
 		HttpOnly: true,
 		Path:     "/",
@@ -69,6 +180,12 @@ func noSurf(next http.Handler) http.Handler {
 
 func (app *App) authenticate(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// already authenticated by token
+		if app.isAuthenticated(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		// get id from session
 		id := app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
 
@@ -82,17 +199,62 @@ func (app *App) authenticate(next http.Handler) http.Handler {
 		exists, err := app.users.Exists(id)
 
 		if err != nil {
-			app.serverError(w, err)
+			app.serverError(w, r, err)
 			return
 		}
 
 		// if exists set true in req context
 		if exists {
 			ctx := context.WithValue(r.Context(), isAuthenticatedContextKey, true)
+			ctx = context.WithValue(ctx, authenticatedUserIDContextKey, id)
 			r = r.WithContext(ctx)
+			setRequestUserID(r, id)
 		}
 
 		next.ServeHTTP(w, r)
 	})
 
 }
+
+// authenticateToken authenticates requests with personal access token
+// sent as "Authorization: Bearer <token>" header.
+// Must run before noSurf and authenticate.
+func (app *App) authenticateToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Authorization")
+
+		header := r.Header.Get("Authorization")
+
+		if header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		scheme, token, ok := strings.Cut(header, " ")
+
+		if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			app.apiClientError(w, http.StatusUnauthorized)
+			return
+		}
+
+		id, err := app.tokens.Authenticate(token)
+
+		if err != nil {
+			if errors.Is(err, models.ErrInvalidCredentials) {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				app.apiClientError(w, http.StatusUnauthorized)
+			} else {
+				app.serverError(w, r, err)
+			}
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), isAuthenticatedContextKey, true)
+		ctx = context.WithValue(ctx, authenticatedUserIDContextKey, id)
+		ctx = context.WithValue(ctx, isTokenAuthenticatedContextKey, true)
+		setRequestUserID(r, id)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -1,10 +1,16 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"snippetbox/internal/models/mocks"
 	"snippetbox/internal/tests"
+	"strings"
 	"testing"
 )
 
@@ -52,3 +58,206 @@ func Test_headerMiddleware(t *testing.T) {
 
 	tests.Equal(t, string(body), "OK")
 }
+
+func Test_authenticateToken(t *testing.T) {
+	app := newTestApp(t)
+
+	testCases := []struct {
+		name         string
+		header       string
+		expCode      int
+		expAuth      bool
+		expUserID    int
+		expTokenAuth bool
+	}{
+		{
+			name:    "No header",
+			expCode: http.StatusOK,
+		},
+		{
+			name:         "Valid token",
+			header:       "Bearer " + mocks.ValidToken,
+			expCode:      http.StatusOK,
+			expAuth:      true,
+			expUserID:    1,
+			expTokenAuth: true,
+		},
+		{
+			name:    "Invalid token",
+			header:  "Bearer sbx_invalid",
+			expCode: http.StatusUnauthorized,
+		},
+		{
+			name:    "Wrong scheme",
+			header:  "Basic dXNlcjpwYXNz",
+			expCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+
+			r, err := http.NewRequest(http.MethodGet, "/", nil)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				tests.Equal(t, app.isAuthenticated(r), tt.expAuth)
+				tests.Equal(t, app.authenticatedUserID(r), tt.expUserID)
+				tests.Equal(t, isTokenAuthenticated(r), tt.expTokenAuth)
+				w.Write([]byte("OK"))
+			})
+
+			app.authenticateToken(next).ServeHTTP(rr, r)
+
+			tests.Equal(t, rr.Result().StatusCode, tt.expCode)
+		})
+	}
+}
+
+func Test_tagRequest(t *testing.T) {
+	testCases := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{name: "Generated", incoming: "", keep: false},
+		{name: "Propagated", incoming: "lb-1234.abc_DEF", keep: true},
+		{name: "Invalid characters", incoming: "id\nwith newline", keep: false},
+		{name: "Too long", incoming: strings.Repeat("a", 65), keep: false},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+
+			r, err := http.NewRequest(http.MethodGet, "/", nil)
+			tests.NilError(t, err)
+
+			if tt.incoming != "" {
+				r.Header.Set("X-Request-ID", tt.incoming)
+			}
+
+			var seen string
+
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = requestID(r)
+			})
+
+			tagRequest(next).ServeHTTP(rr, r)
+
+			header := rr.Result().Header.Get("X-Request-ID")
+
+			tests.Equal(t, seen, header)
+
+			if tt.keep {
+				tests.Equal(t, header, tt.incoming)
+			} else {
+				tests.Equal(t, len(header), 32)
+			}
+		})
+	}
+}
+
+func Test_logRequests(t *testing.T) {
+	app := newTestApp(t)
+
+	buf := new(bytes.Buffer)
+	app.logger = slog.New(slog.NewJSONHandler(buf, nil))
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL+"/api/v1/snippets/mockSnippet1", nil)
+	tests.NilError(t, err)
+
+	req.Header.Set("Authorization", "Bearer "+mocks.ValidToken)
+	req.Header.Set("X-Request-ID", "test-request")
+
+	rs, err := ts.Client().Do(req)
+	tests.NilError(t, err)
+	rs.Body.Close()
+
+	var entry struct {
+		Msg       string `json:"msg"`
+		RequestID string `json:"request_id"`
+		Method    string `json:"method"`
+		Route     string `json:"route"`
+		Status    int    `json:"status"`
+		Bytes     int    `json:"bytes"`
+		UserID    int    `json:"user_id"`
+	}
+
+	err = json.Unmarshal(buf.Bytes(), &entry)
+	tests.NilError(t, err)
+
+	tests.Equal(t, entry.Msg, "request")
+	tests.Equal(t, entry.RequestID, "test-request")
+	tests.Equal(t, entry.Method, http.MethodGet)
+	tests.Equal(t, entry.Route, "/api/v1/snippets/{slug}")
+	tests.Equal(t, entry.Status, http.StatusOK)
+	tests.Equal(t, entry.Bytes > 0, true)
+	tests.Equal(t, entry.UserID, 1)
+}
+
+func Test_loggedURI(t *testing.T) {
+	testCases := []struct {
+		name string
+		uri  string
+		want string
+	}{
+		{
+			name: "Plain",
+			uri:  "/snippet/view/abc?page=2",
+			want: "/snippet/view/abc?page=2",
+		},
+		{
+			name: "Reset token in path",
+			uri:  "/user/password/reset/s3cret",
+			want: "/user/password/reset/REDACTED",
+		},
+		{
+			name: "Token in query",
+			uri:  "/user/verify?token=s3cret",
+			want: "/user/verify?token=REDACTED",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.uri, nil)
+
+			tests.Equal(t, loggedURI(r), tt.want)
+		})
+	}
+}
+
+func Test_serverErrorRequestID(t *testing.T) {
+	app := newTestApp(t)
+
+	buf := new(bytes.Buffer)
+	app.logger = slog.New(slog.NewJSONHandler(buf, nil))
+
+	rr := httptest.NewRecorder()
+
+	r, err := http.NewRequest(http.MethodGet, "/", nil)
+	tests.NilError(t, err)
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		app.serverError(w, r, errors.New("db is down"))
+	})
+
+	tagRequest(next).ServeHTTP(rr, r)
+
+	tests.Equal(t, rr.Code, http.StatusInternalServerError)
+	tests.StringContains(t, buf.String(), `"msg":"db is down"`)
+	tests.StringContains(t, buf.String(), `"request_id":"`+rr.Header().Get("X-Request-ID")+`"`)
+	tests.StringContains(t, buf.String(), `"trace":"goroutine`)
+}
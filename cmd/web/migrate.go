@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"snippetbox/internal/models/migrations"
+	"text/tabwriter"
+)
+
+const migrateUsage = "usage: web migrate up|down|status [flags]"
+
+// runMigrate handles "web migrate <command> [flags]", args are after "migrate".
+// Flags, config file and environment are same as for server.
+func runMigrate(args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	command := args[0]
+
+	if command != "up" && command != "down" && command != "status" {
+		return fmt.Errorf("unknown migrate command %q\n%s", command, migrateUsage)
+	}
+
+	cfg, _, err := loadConfig(args[1:], os.LookupEnv)
+
+	if err != nil {
+		return err
+	}
+
+	err = cfg.validateDSN()
+
+	if err != nil {
+		return err
+	}
+
+	db, err := openDB(cfg.DSN)
+
+	if err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	switch command {
+	case "up":
+		applied, err := migrations.Up(db)
+		printMigrations(stdout, "applied", applied)
+		return err
+	case "down":
+		// one step at a time, reverting everything by accident is too easy otherwise
+		reverted, err := migrations.Down(db, 1)
+		printMigrations(stdout, "reverted", reverted)
+		return err
+	default:
+		statuses, err := migrations.Statuses(db)
+
+		if err != nil {
+			return err
+		}
+
+		printStatuses(stdout, statuses)
+		return nil
+	}
+}
+
+func printMigrations(w io.Writer, action string, ms []*migrations.Migration) {
+	if len(ms) == 0 {
+		fmt.Fprintf(w, "nothing %s\n", action)
+		return
+	}
+
+	for _, m := range ms {
+		fmt.Fprintf(w, "%s %04d_%s\n", action, m.Version, m.Name)
+	}
+}
+
+func printStatuses(w io.Writer, statuses []*migrations.Status) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED")
+
+	for _, s := range statuses {
+		applied := "pending"
+
+		if !s.Applied.IsZero() {
+			applied = s.Applied.UTC().Format("2006-01-02 15:04:05")
+		}
+
+		fmt.Fprintf(tw, "%04d\t%s\t%s\n", s.Version, s.Name, applied)
+	}
+
+	tw.Flush()
+}
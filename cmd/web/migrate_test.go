@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"snippetbox/internal/models/migrations"
+	"snippetbox/internal/tests"
+	"testing"
+	"time"
+)
+
+func Test_runMigrateErrors(t *testing.T) {
+	testCases := []struct {
+		name   string
+		args   []string
+		expErr string
+	}{
+		{
+			name:   "No command",
+			expErr: migrateUsage,
+		},
+		{
+			name:   "Unknown command",
+			args:   []string{"sideways"},
+			expErr: `unknown migrate command "sideways"`,
+		},
+		{
+			name:   "DSN without parseTime",
+			args:   []string{"status", "-dsn", "root:password@/snippetbox"},
+			expErr: "dsn: parseTime=true is required",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+
+			err := runMigrate(tt.args, &out)
+
+			if err == nil {
+				t.Fatal("expected error")
+			}
+
+			tests.StringContains(t, err.Error(), tt.expErr)
+			tests.Equal(t, out.String(), "")
+		})
+	}
+}
+
+func Test_printStatuses(t *testing.T) {
+	var out bytes.Buffer
+
+	printStatuses(&out, []*migrations.Status{
+		{
+			Migration: migrations.Migration{Version: 1, Name: "create_users"},
+			Applied:   time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC),
+		},
+		{
+			Migration: migrations.Migration{Version: 2, Name: "create_snippets"},
+		},
+	})
+
+	expected := "VERSION  NAME             APPLIED\n" +
+		"0001     create_users     2023-05-01 10:00:00\n" +
+		"0002     create_snippets  pending\n"
+
+	tests.Equal(t, out.String(), expected)
+}
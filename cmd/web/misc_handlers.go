@@ -1,12 +1,31 @@
 package main
 
-import "net/http"
+import (
+	"bytes"
+	"net/http"
+	"snippetbox/internal/highlight"
+)
 
 func (app *App) AboutView(w http.ResponseWriter, r *http.Request) {
 	data := app.newTemplateData(r)
-	app.render(w, http.StatusOK, "about.tmpl.html", data)
+	app.render(w, r, http.StatusOK, "about.tmpl.html", data)
 }
 
 func ping(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("OK"))
 }
+
+// highlightCSS serves stylesheet for syntax highlighted snippets
+func (app *App) highlightCSS(w http.ResponseWriter, r *http.Request) {
+	buf := new(bytes.Buffer)
+
+	err := highlight.CSS(buf)
+
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/css; charset=utf-8")
+	buf.WriteTo(w)
+}
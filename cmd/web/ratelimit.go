@@ -0,0 +1,243 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// bucket holds tokens of single client, one is taken by each request
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter is token bucket limiter. Bucket holds policy.Requests tokens
+// and is refilled evenly over policy.Period.
+type rateLimiter struct {
+	policy  rateLimitPolicy
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	// buckets idle since then are full, so they are deleted
+	lastSweep time.Time
+	// clock, replaced in tests
+	now func() time.Time
+}
+
+func newRateLimiter(policy rateLimitPolicy) *rateLimiter {
+	return &rateLimiter{
+		policy:    policy,
+		buckets:   map[string]*bucket{},
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// rateLimitResult describes bucket of client after request
+type rateLimitResult struct {
+	allowed   bool
+	remaining int
+	// until bucket is full again
+	reset time.Duration
+	// until next request is allowed, 0 if it is now
+	retryAfter time.Duration
+}
+
+// allow takes token from bucket of key
+func (l *rateLimiter) allow(key string) rateLimitResult {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	capacity := float64(l.policy.Requests)
+	// tokens per second
+	rate := capacity / l.policy.Period.Seconds()
+
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+
+	if !ok {
+		b = &bucket{tokens: capacity, last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	res := rateLimitResult{}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		res.allowed = true
+	} else {
+		res.retryAfter = seconds((1 - b.tokens) / rate)
+	}
+
+	res.remaining = int(b.tokens)
+	res.reset = seconds((capacity - b.tokens) / rate)
+
+	return res
+}
+
+// sweep deletes buckets not used for whole period, they would be full
+// anyway. It runs at most once per period, so memory is bounded by
+// number of clients seen in period.
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.policy.Period {
+		return
+	}
+
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= l.policy.Period {
+			delete(l.buckets, key)
+		}
+	}
+
+	l.lastSweep = now
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// headerSeconds rounds d up, so client waiting that long isnt rejected again
+func headerSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+// rateLimit limits requests of group per authenticated user, or per client IP
+// for anonymous requests. Must run after authentication middlewares.
+// reject writes response of rejected request.
+func (app *App) rateLimit(group string, policy rateLimitPolicy, reject func(http.ResponseWriter, int)) func(http.Handler) http.Handler {
+	if policy.Requests == 0 {
+		return func(next http.Handler) http.Handler {
+			return next
+		}
+	}
+
+	limiter := newRateLimiter(policy)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			res := limiter.allow(rateLimitKey(r))
+
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(policy.Requests))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(res.remaining))
+			w.Header().Set("RateLimit-Reset", headerSeconds(res.reset))
+			w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%s", policy.Requests, headerSeconds(policy.Period)))
+
+			if !res.allowed {
+				app.metrics.rateLimited.WithLabelValues(group).Inc()
+				w.Header().Set("Retry-After", headerSeconds(res.retryAfter))
+				reject(w, http.StatusTooManyRequests)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// rateLimitKey returns user for authenticated requests, otherwise client IP.
+// IPv6 clients usually get whole /64, so it is limited as one.
+func rateLimitKey(r *http.Request) string {
+	if id, ok := r.Context().Value(authenticatedUserIDContextKey).(int); ok && id != 0 {
+		return "user:" + strconv.Itoa(id)
+	}
+
+	ip := clientIP(r)
+
+	addr, err := netip.ParseAddr(ip)
+
+	if err == nil && addr.Is6() && !addr.Is4In6() {
+		prefix, _ := addr.WithZone("").Prefix(64)
+		return "ip:" + prefix.String()
+	}
+
+	return "ip:" + ip
+}
+
+// parseTrustedProxies accepts IPs and CIDRs
+func parseTrustedProxies(proxies []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(proxies))
+
+	for _, proxy := range proxies {
+		if strings.Contains(proxy, "/") {
+			prefix, err := netip.ParsePrefix(proxy)
+
+			if err != nil {
+				return nil, err
+			}
+
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(proxy)
+
+		if err != nil {
+			return nil, err
+		}
+
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+
+	return prefixes, nil
+}
+
+// realIP replaces RemoteAddr with client IP from X-Forwarded-For when
+// request came from trusted proxy. Addresses are checked from right,
+// first one not belonging to trusted proxy is client, those left of it
+// could be set by client itself.
+func realIP(trusted []netip.Prefix) func(http.Handler) http.Handler {
+	isTrusted := func(addr netip.Addr) bool {
+		addr = addr.Unmap()
+
+		for _, prefix := range trusted {
+			if prefix.Contains(addr) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			peer, err := netip.ParseAddr(clientIP(r))
+
+			if err != nil || !isTrusted(peer) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			client := peer
+			var forwarded []string
+
+			for _, header := range r.Header.Values("X-Forwarded-For") {
+				forwarded = append(forwarded, strings.Split(header, ",")...)
+			}
+
+			for i := len(forwarded) - 1; i >= 0 && isTrusted(client); i-- {
+				addr, err := netip.ParseAddr(strings.TrimSpace(forwarded[i]))
+
+				// garbage is added by client, not by proxy
+				if err != nil {
+					break
+				}
+
+				client = addr
+			}
+
+			r.RemoteAddr = client.Unmap().String()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
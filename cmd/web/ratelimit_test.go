@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"snippetbox/internal/tests"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func newTestRateLimiter(policy rateLimitPolicy) (*rateLimiter, *time.Time) {
+	limiter := newRateLimiter(policy)
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter.now = func() time.Time { return now }
+	limiter.lastSweep = now
+
+	return limiter, &now
+}
+
+func Test_rateLimiter(t *testing.T) {
+	limiter, now := newTestRateLimiter(rateLimitPolicy{Requests: 3, Period: 3 * time.Second})
+
+	for i := 2; i >= 0; i-- {
+		res := limiter.allow("ip:10.0.0.1")
+		tests.Equal(t, res.allowed, true)
+		tests.Equal(t, res.remaining, i)
+	}
+
+	res := limiter.allow("ip:10.0.0.1")
+	tests.Equal(t, res.allowed, false)
+	tests.Equal(t, res.retryAfter, time.Second)
+	tests.Equal(t, res.reset, 3*time.Second)
+
+	// other clients have own bucket
+	tests.Equal(t, limiter.allow("ip:10.0.0.2").allowed, true)
+
+	// one token is refilled each second
+	*now = now.Add(time.Second)
+
+	res = limiter.allow("ip:10.0.0.1")
+	tests.Equal(t, res.allowed, true)
+	tests.Equal(t, res.remaining, 0)
+
+	tests.Equal(t, limiter.allow("ip:10.0.0.1").allowed, false)
+
+	// bucket doesnt grow past limit
+	*now = now.Add(time.Hour)
+
+	for i := 0; i < 3; i++ {
+		tests.Equal(t, limiter.allow("ip:10.0.0.1").allowed, true)
+	}
+
+	tests.Equal(t, limiter.allow("ip:10.0.0.1").allowed, false)
+}
+
+func Test_rateLimiterSweep(t *testing.T) {
+	limiter, now := newTestRateLimiter(rateLimitPolicy{Requests: 3, Period: time.Minute})
+
+	limiter.allow("ip:10.0.0.1")
+	limiter.allow("ip:10.0.0.2")
+	tests.Equal(t, len(limiter.buckets), 2)
+
+	*now = now.Add(30 * time.Second)
+	limiter.allow("ip:10.0.0.2")
+	tests.Equal(t, len(limiter.buckets), 2)
+
+	// only bucket idle for whole period is deleted
+	*now = now.Add(30 * time.Second)
+	limiter.allow("ip:10.0.0.3")
+	tests.Equal(t, len(limiter.buckets), 2)
+
+	_, ok := limiter.buckets["ip:10.0.0.1"]
+	tests.Equal(t, ok, false)
+}
+
+func Test_rateLimitKey(t *testing.T) {
+	testCases := []struct {
+		name       string
+		remoteAddr string
+		userID     int
+		expKey     string
+	}{
+		{name: "IPv4", remoteAddr: "10.0.0.1:1234", expKey: "ip:10.0.0.1"},
+		{name: "IPv6", remoteAddr: "[2001:db8:1:2:3::4]:1234", expKey: "ip:2001:db8:1:2::/64"},
+		{name: "No port", remoteAddr: "10.0.0.1", expKey: "ip:10.0.0.1"},
+		{name: "Authenticated", remoteAddr: "10.0.0.1:1234", userID: 7, expKey: "user:7"},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+
+			if tt.userID != 0 {
+				r = r.WithContext(context.WithValue(r.Context(), authenticatedUserIDContextKey, tt.userID))
+			}
+
+			tests.Equal(t, rateLimitKey(r), tt.expKey)
+		})
+	}
+}
+
+func Test_realIP(t *testing.T) {
+	trusted, err := parseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1"})
+	tests.NilError(t, err)
+
+	testCases := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		expIP      string
+	}{
+		{
+			name:       "Untrusted peer",
+			remoteAddr: "203.0.113.9:1234",
+			forwarded:  []string{"198.51.100.1"},
+			expIP:      "203.0.113.9:1234",
+		},
+		{
+			name:       "Trusted peer",
+			remoteAddr: "192.168.1.1:1234",
+			forwarded:  []string{"198.51.100.1"},
+			expIP:      "198.51.100.1",
+		},
+		{
+			name:       "Chain of proxies",
+			remoteAddr: "10.0.0.1:1234",
+			forwarded:  []string{"198.51.100.1, 10.0.0.2", "10.0.0.3"},
+			expIP:      "198.51.100.1",
+		},
+		{
+			name:       "Spoofed by client",
+			remoteAddr: "10.0.0.1:1234",
+			forwarded:  []string{"1.1.1.1, 198.51.100.1"},
+			expIP:      "198.51.100.1",
+		},
+		{
+			name:       "Garbage",
+			remoteAddr: "10.0.0.1:1234",
+			forwarded:  []string{"198.51.100.1, unknown, 10.0.0.2"},
+			expIP:      "10.0.0.2",
+		},
+		{
+			name:       "No header",
+			remoteAddr: "10.0.0.1:1234",
+			expIP:      "10.0.0.1",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+
+			for _, value := range tt.forwarded {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+
+			var ip string
+
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ip = r.RemoteAddr
+			})
+
+			realIP(trusted)(next).ServeHTTP(httptest.NewRecorder(), r)
+
+			tests.Equal(t, ip, tt.expIP)
+		})
+	}
+}
+
+func Test_UserRateLimited(t *testing.T) {
+	app := newTestApp(t)
+	app.config.RateLimit.User = rateLimitPolicy{Requests: 2, Period: time.Minute}
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	for i := 1; i >= 0; i-- {
+		code, header, _ := ts.get(t, "/user/login")
+		tests.Equal(t, code, http.StatusOK)
+		tests.Equal(t, header.Get("RateLimit-Limit"), "2")
+		tests.Equal(t, header.Get("RateLimit-Remaining"), strconv.Itoa(i))
+		tests.Equal(t, header.Get("RateLimit-Policy"), "2;w=60")
+	}
+
+	code, header, _ := ts.get(t, "/user/signup")
+	tests.Equal(t, code, http.StatusTooManyRequests)
+	tests.Equal(t, header.Get("Retry-After"), "30")
+	tests.Equal(t, header.Get("RateLimit-Remaining"), "0")
+	tests.Equal(t, header.Get("RateLimit-Reset"), "60")
+
+	// other groups have own limits
+	code, _, _ = ts.get(t, "/")
+	tests.Equal(t, code, http.StatusOK)
+}
+
+func Test_SnippetCreateRateLimited(t *testing.T) {
+	app := newTestApp(t)
+	app.config.RateLimit.SnippetCreate = rateLimitPolicy{Requests: 1, Period: time.Minute}
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	csrfToken := ts.login(t)
+
+	create := func() int {
+		form := url.Values{}
+		form.Add("title", "Title")
+		form.Add("content", "Content")
+		form.Add("language", "plaintext")
+		form.Add("visibility", "public")
+		form.Add("expires", "7")
+		form.Add("csrf_token", csrfToken)
+
+		code, _, _ := ts.postForm(t, "/snippet/create", form)
+
+		return code
+	}
+
+	// form isnt limited
+	for i := 0; i < 2; i++ {
+		code, header, _ := ts.get(t, "/snippet/create")
+		tests.Equal(t, code, http.StatusOK)
+		tests.Equal(t, header.Get("RateLimit-Limit"), "")
+	}
+
+	tests.Equal(t, create(), http.StatusSeeOther)
+	tests.Equal(t, create(), http.StatusTooManyRequests)
+}
+
+func Test_APIRateLimited(t *testing.T) {
+	app := newTestApp(t)
+	app.config.RateLimit.API = rateLimitPolicy{Requests: 1, Period: time.Minute}
+
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	code, _, _ := ts.get(t, "/api/v1/snippets")
+	tests.Equal(t, code, http.StatusOK)
+
+	code, header, body := ts.get(t, "/api/v1/snippets")
+	tests.Equal(t, code, http.StatusTooManyRequests)
+	tests.Equal(t, header.Get("Retry-After"), "60")
+	tests.StringContains(t, body, `"status":429`)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"mime"
+	"net/http"
+	"snippetbox/internal/highlight"
+	"snippetbox/internal/models"
+	"strings"
+	"unicode"
+)
+
+// longest title part of download filename
+const maxFilenameLength = 50
+
+func (app *App) SnippetRaw(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.viewableSnippet(w, r)
+
+	if !ok {
+		return
+	}
+
+	serveSnippetContent(w, r, snippet)
+}
+
+func (app *App) SnippetDownload(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.viewableSnippet(w, r)
+
+	if !ok {
+		return
+	}
+
+	disposition := mime.FormatMediaType("attachment", map[string]string{
+		"filename": downloadFilename(snippet),
+	})
+
+	w.Header().Set("Content-Disposition", disposition)
+
+	serveSnippetContent(w, r, snippet)
+}
+
+// serveSnippetContent writes snippet content as plain text.
+// http.ServeContent answers conditional requests using ETag and Last-Modified.
+func serveSnippetContent(w http.ResponseWriter, r *http.Request, snippet *models.Snippet) {
+	sum := sha256.Sum256([]byte(snippet.Content))
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+
+	// response depends on session, shared caches must not store it
+	if snippet.Visibility != models.VisibilityPublic {
+		w.Header().Set("Cache-Control", "private")
+	}
+
+	http.ServeContent(w, r, "", snippet.Updated, strings.NewReader(snippet.Content))
+}
+
+// downloadFilename turns snippet title into file name like "my-title.go"
+func downloadFilename(snippet *models.Snippet) string {
+	var b strings.Builder
+
+	dash := false
+
+	for _, r := range strings.ToLower(snippet.Title) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+
+		if b.Len() >= maxFilenameLength {
+			break
+		}
+	}
+
+	name := b.String()
+
+	if name == "" {
+		name = "snippet"
+	}
+
+	return name + highlight.Extension(snippet.Language)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// expiredDeleter is part of models.SnippetRepo used by reaper
+type expiredDeleter interface {
+	DeleteExpired(before time.Time, limit int) (int, error)
+}
+
+// reaper periodically deletes snippets which expired more than grace ago
+type reaper struct {
+	snippets  expiredDeleter
+	logger    *slog.Logger
+	interval  time.Duration
+	grace     time.Duration
+	batchSize int
+	// clock, replaced in tests
+	now func() time.Time
+}
+
+func newReaper(cfg *config, snippets expiredDeleter, logger *slog.Logger) *reaper {
+	return &reaper{
+		snippets:  snippets,
+		logger:    logger,
+		interval:  cfg.Reaper.Interval,
+		grace:     cfg.Reaper.Grace,
+		batchSize: cfg.Reaper.Batch,
+		now:       time.Now,
+	}
+}
+
+// start runs reaper in background until ctx is cancelled,
+// returned channel is closed when it has stopped
+func (rp *reaper) start(ctx context.Context) <-chan struct{} {
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(rp.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				deleted, err := rp.reap(ctx)
+
+				if err != nil {
+					rp.logger.Error("reaping expired snippets", "error", err)
+				}
+
+				if deleted > 0 {
+					rp.logger.Info("reaped expired snippets", "count", deleted)
+				}
+			}
+		}
+	}()
+
+	return done
+}
+
+// reap deletes expired snippets batch by batch, so single query
+// doesnt lock table for long, and returns number of deleted snippets
+func (rp *reaper) reap(ctx context.Context) (int, error) {
+	before := rp.now().Add(-rp.grace)
+	total := 0
+
+	for ctx.Err() == nil {
+		deleted, err := rp.snippets.DeleteExpired(before, rp.batchSize)
+		total += deleted
+
+		if err != nil {
+			return total, err
+		}
+
+		if deleted < rp.batchSize {
+			break
+		}
+	}
+
+	return total, nil
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"snippetbox/internal/tests"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeDeleter holds given number of expired snippets
+type fakeDeleter struct {
+	mu      sync.Mutex
+	expired int
+	err     error
+	calls   int
+	before  time.Time
+}
+
+func (d *fakeDeleter) DeleteExpired(before time.Time, limit int) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.calls++
+	d.before = before
+
+	if d.err != nil {
+		return 0, d.err
+	}
+
+	deleted := d.expired
+
+	if deleted > limit {
+		deleted = limit
+	}
+
+	d.expired -= deleted
+
+	return deleted, nil
+}
+
+func newTestReaper(d *fakeDeleter, now time.Time) *reaper {
+	return &reaper{
+		snippets:  d,
+		logger:    slog.New(slog.NewJSONHandler(io.Discard, nil)),
+		interval:  time.Millisecond,
+		grace:     time.Hour,
+		batchSize: 10,
+		now:       func() time.Time { return now },
+	}
+}
+
+func Test_reaperReap(t *testing.T) {
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name     string
+		expired  int
+		err      error
+		expTotal int
+		expCalls int
+	}{
+		{
+			name:     "Nothing expired",
+			expired:  0,
+			expTotal: 0,
+			expCalls: 1,
+		},
+		{
+			name:     "Single batch",
+			expired:  7,
+			expTotal: 7,
+			expCalls: 1,
+		},
+		{
+			name:     "Several batches",
+			expired:  25,
+			expTotal: 25,
+			expCalls: 3,
+		},
+		{
+			name:     "Exact batch",
+			expired:  10,
+			expTotal: 10,
+			expCalls: 2,
+		},
+		{
+			name:     "Error",
+			expired:  5,
+			err:      errors.New("db is down"),
+			expTotal: 0,
+			expCalls: 1,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &fakeDeleter{expired: tt.expired, err: tt.err}
+			rp := newTestReaper(d, now)
+
+			total, err := rp.reap(context.Background())
+
+			tests.Equal(t, errors.Is(err, tt.err), true)
+			tests.Equal(t, total, tt.expTotal)
+			tests.Equal(t, d.calls, tt.expCalls)
+			// snippets within grace period are kept
+			tests.Equal(t, d.before, now.Add(-time.Hour))
+		})
+	}
+
+	t.Run("Cancelled", func(t *testing.T) {
+		d := &fakeDeleter{expired: 100}
+		rp := newTestReaper(d, now)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		total, err := rp.reap(ctx)
+
+		tests.NilError(t, err)
+		tests.Equal(t, total, 0)
+		tests.Equal(t, d.calls, 0)
+	})
+}
+
+func Test_reaperStart(t *testing.T) {
+	d := &fakeDeleter{expired: 15}
+	rp := newTestReaper(d, time.Now())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := rp.start(ctx)
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for {
+		d.mu.Lock()
+		expired := d.expired
+		d.mu.Unlock()
+
+		if expired == 0 {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("reaper didnt delete expired snippets")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("reaper didnt stop")
+	}
+}
+
+// expiringDeleter holds expiry times of snippets
+type expiringDeleter struct {
+	expires []time.Time
+}
+
+func (d *expiringDeleter) DeleteExpired(before time.Time, limit int) (int, error) {
+	var kept []time.Time
+	deleted := 0
+
+	for _, expires := range d.expires {
+		if expires.After(before) || deleted == limit {
+			kept = append(kept, expires)
+		} else {
+			deleted++
+		}
+	}
+
+	d.expires = kept
+
+	return deleted, nil
+}
+
+func Test_reaperDefaultGrace(t *testing.T) {
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	d := &expiringDeleter{expires: []time.Time{
+		now.Add(day),
+		now.Add(-day),
+		now.Add(-29 * day),
+		now.Add(-31 * day),
+	}}
+
+	rp := newReaper(defaultConfig(), d, slog.New(slog.NewJSONHandler(io.Discard, nil)))
+	rp.now = func() time.Time { return now }
+
+	total, err := rp.reap(context.Background())
+
+	tests.NilError(t, err)
+	tests.Equal(t, total, 1)
+	// expired snippets inside grace period can still be renewed
+	tests.Equal(t, len(d.expires), 3)
+	tests.Equal(t, d.expires[2], now.Add(-29*day))
+}
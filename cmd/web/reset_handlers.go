@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"snippetbox/internal/mailer"
+	"snippetbox/internal/models"
+	"snippetbox/internal/validator"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const resetEmailBody = `Hi %s,
+
+someone asked to reset password of your Snippetbox account. To choose new password open link below:
+
+%s
+
+Link is valid for %s and can be used once. If you didn't ask for it, ignore this email, your password stays the same.
+`
+
+type PasswordForgotForm struct {
+	Email               string `form:"email"`
+	validator.Validator `form:"-"`
+}
+
+type PasswordResetForm struct {
+	Token                   string `form:"-"`
+	NewPassword             string `form:"newPassword"`
+	NewPasswordConfirmation string `form:"newPasswordConfirmation"`
+	validator.Validator     `form:"-"`
+}
+
+func (app *App) sendPasswordResetEmail(user *models.User) error {
+	ttl := app.config.Mail.ResetTTL
+
+	token, err := app.resets.Create(user.Id, ttl)
+
+	if err != nil {
+		return err
+	}
+
+	link := app.config.BaseURL + "/user/password/reset/" + url.PathEscape(token)
+
+	return app.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your Snippetbox password",
+		Body:    fmt.Sprintf(resetEmailBody, user.Name, link, ttl),
+	})
+}
+
+// revokeSessions destroys every stored session of user. Store has no index
+// by user, so all sessions are loaded, fine while there arent too many.
+func (app *App) revokeSessions(ctx context.Context, userID int) error {
+	return app.sessionManager.Iterate(ctx, func(ctx context.Context) error {
+		if app.sessionManager.GetInt(ctx, "authenticatedUserID") != userID {
+			return nil
+		}
+
+		return app.sessionManager.Destroy(ctx)
+	})
+}
+
+func (app *App) UserPasswordForgot(w http.ResponseWriter, r *http.Request) {
+	data := app.newTemplateData(r)
+	data.Form = PasswordForgotForm{}
+	app.render(w, r, http.StatusOK, "forgot.tmpl.html", data)
+}
+
+func (app *App) UserPasswordForgotPost(w http.ResponseWriter, r *http.Request) {
+	var form PasswordForgotForm
+	err := app.DecodePostForm(r, &form)
+
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form.CheckField(validator.NotBlank(form.Email), "email", "This field cant be empty")
+	form.CheckField(validator.Matches(form.Email, validator.EmailRegex), "email", "This field must be valid email")
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, r, http.StatusUnprocessableEntity, "forgot.tmpl.html", data)
+		return
+	}
+
+	user, err := app.users.GetByEmail(form.Email)
+
+	if err != nil && !errors.Is(err, models.ErrNoRecord) {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// form is open to anyone, so it mustnt tell which emails have account.
+	// Unknown emails get same flash and slow smtp doesnt delay known ones.
+	if err == nil {
+		app.background(func() error {
+			return app.sendPasswordResetEmail(user)
+		})
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "If account with that email exists, we've sent link to reset password.")
+	http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+}
+
+func (app *App) UserPasswordReset(w http.ResponseWriter, r *http.Request) {
+	token := chi.URLParam(r, "token")
+
+	valid, err := app.resets.Valid(token)
+
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if !valid {
+		app.invalidResetLink(w, r)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Form = PasswordResetForm{Token: token}
+	app.render(w, r, http.StatusOK, "reset.tmpl.html", data)
+}
+
+func (app *App) UserPasswordResetPost(w http.ResponseWriter, r *http.Request) {
+	var form PasswordResetForm
+	err := app.DecodePostForm(r, &form)
+
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form.Token = chi.URLParam(r, "token")
+
+	form.CheckField(validator.NotBlank(form.NewPassword), "newPassword", "This field cant be empty")
+	form.CheckField(validator.NotBlank(form.NewPasswordConfirmation), "newPasswordConfirmation", "This field cant be empty")
+	form.CheckField(validator.MinChars(form.NewPassword, 8), "newPassword", "New password cant be less then 8 characters")
+	form.CheckField(validator.Equals(form.NewPassword, form.NewPasswordConfirmation), "newPasswordConfirmation", "Passwords do not match")
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, r, http.StatusUnprocessableEntity, "reset.tmpl.html", data)
+		return
+	}
+
+	userID, err := app.resets.Consume(form.Token)
+
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidCredentials) {
+			app.invalidResetLink(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	err = app.users.PasswordSet(userID, form.NewPassword)
+
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.revokeSessions(r.Context(), userID)
+
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// whoever had access to account could have created api tokens too
+	err = app.tokens.DeleteAllForUser(userID)
+
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// current session is saved after handler, so it has to be logged out too
+	err = app.sessionManager.RenewToken(r.Context())
+
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.sessionManager.Remove(r.Context(), "authenticatedUserID")
+	app.sessionManager.Put(r.Context(), "flash", "Your password has been reset, login with new password.")
+	http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+}
+
+func (app *App) invalidResetLink(w http.ResponseWriter, r *http.Request) {
+	app.sessionManager.Put(r.Context(), "flash", "Password reset link is invalid or has expired, request new one below.")
+	http.Redirect(w, r, "/user/password/forgot", http.StatusSeeOther)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"snippetbox/internal/diff"
+	"snippetbox/internal/models"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// lines of unchanged content shown around each change
+const diffContext = 3
+
+func (app *App) SnippetHistory(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.viewableSnippet(w, r)
+
+	if !ok {
+		return
+	}
+
+	revisions, err := app.snippets.Revisions(snippet.ID)
+
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	data.Revisions = revisions
+
+	app.render(w, r, http.StatusOK, "history.tmpl.html", data)
+}
+
+func (app *App) SnippetDiff(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.viewableSnippet(w, r)
+
+	if !ok {
+		return
+	}
+
+	from, ok := app.revisionFromQuery(w, r, snippet.ID, "from")
+
+	if !ok {
+		return
+	}
+
+	to, ok := app.revisionFromQuery(w, r, snippet.ID, "to")
+
+	if !ok {
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	data.FromRevision = from
+	data.ToRevision = to
+	data.Diff = diff.Unified(from.Content, to.Content, diffContext)
+
+	app.render(w, r, http.StatusOK, "diff.tmpl.html", data)
+}
+
+func (app *App) SnippetRestorePost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.ownedSnippet(w, r)
+
+	if !ok {
+		return
+	}
+
+	number, err := strconv.Atoi(chi.URLParam(r, "revision"))
+
+	if err != nil || number < 1 {
+		app.notFound(w)
+		return
+	}
+
+	err = app.snippets.Restore(snippet.ID, number, app.authenticatedUserID(r))
+
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", fmt.Sprintf("Revision %d has been restored.", number))
+	http.Redirect(w, r, "/snippet/view/"+snippet.Slug, http.StatusSeeOther)
+}
+
+// revisionFromQuery loads snippet revision with number from url query param.
+// On failure response is already written.
+func (app *App) revisionFromQuery(w http.ResponseWriter, r *http.Request, snippetID int, key string) (*models.Revision, bool) {
+	number := queryInt(r, key, 0)
+
+	if number == 0 {
+		app.notFound(w)
+		return nil, false
+	}
+
+	rev, err := app.snippets.Revision(snippetID, number)
+
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return nil, false
+	}
+
+	return rev, true
+}
@@ -11,45 +11,97 @@ func (app *App) routes() http.Handler {
 	router := chi.NewRouter()
 
 	// global middlewares
-	router.Use(app.recoverPanic, app.logRequests, headerMiddleware)
+	router.Use(tagRequest, realIP(app.trustedProxies), app.logRequests, app.collectMetrics, app.recoverPanic, headerMiddleware)
 	// custom not found
 	router.NotFound(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		app.notFound(w)
 	}))
 
 	router.HandleFunc("/ping", ping)
+	router.Get("/healthz", app.healthz)
+	router.Get("/readyz", app.readyz)
+	router.With(app.allowIPs(app.metricsAllow)).Handle("/metrics", app.metrics.handler())
 
 	// file server with embed filesystem that serves static content
 	fileServer := http.FileServer(http.FS(ui.Files))
 	router.Handle("/static/*", fileServer)
+	router.Get("/static/css/highlight.css", app.highlightCSS)
 
 	router.Route("/user", func(r chi.Router) {
-		r.Use(app.sessionManager.LoadAndSave, noSurf, app.authenticate)
+		r.Use(app.sessionManager.LoadAndSave, noSurf, app.authenticate,
+			app.rateLimit("user", app.config.RateLimit.User, app.clientError))
 		r.Get("/signup", app.UserSignup)
 		r.Get("/login", app.UserLogin)
 		r.Post("/signup", app.UserSignupPost)
 		r.Post("/login", app.UserLoginPost)
+		r.Get("/login/2fa", app.UserLoginTwoFactor)
+		r.Post("/login/2fa", app.UserLoginTwoFactorPost)
 		r.Post("/logout", app.UserLogoutPost)
+		r.Get("/verify", app.UserVerify)
+		r.Get("/verify/resend", app.UserVerifyResend)
+		r.Post("/verify/resend", app.UserVerifyResendPost)
+		r.Get("/password/forgot", app.UserPasswordForgot)
+		r.Post("/password/forgot", app.UserPasswordForgotPost)
+		r.Get("/password/reset/{token}", app.UserPasswordReset)
+		r.Post("/password/reset/{token}", app.UserPasswordResetPost)
 	})
 
 	router.Route("/account", func(r chi.Router) {
-		r.Use(app.sessionManager.LoadAndSave, noSurf, app.authenticate, app.requireAuth)
+		r.Use(app.sessionManager.LoadAndSave, app.authenticateToken, noSurf, app.authenticate, app.requireAuth)
 
 		r.Get("/view", app.AccountView)
+		r.Get("/snippets", app.AccountSnippets)
 		r.Get("/password/update", app.AccountPasswordUpdateView)
 		r.Post("/password/update", app.AccountPasswordUpdate)
+		r.Get("/tokens", app.AccountTokens)
+		r.Post("/tokens", app.AccountTokenCreatePost)
+		r.Post("/tokens/revoke/{id}", app.AccountTokenRevokePost)
+		r.Get("/2fa", app.AccountTwoFactor)
+		r.Get("/2fa/qr", app.AccountTwoFactorQR)
+		r.Post("/2fa/enable", app.AccountTwoFactorEnablePost)
+		r.Post("/2fa/disable", app.AccountTwoFactorDisablePost)
 	})
 
+	// only creating is limited, form itself is cheap
+	limitSnippetCreate := app.rateLimit("snippet_create", app.config.RateLimit.SnippetCreate, app.clientError)
+
 	// routes with session middleware
 	router.Group(func(r chi.Router) {
-		r.Use(app.sessionManager.LoadAndSave, noSurf, app.authenticate)
+		r.Use(app.sessionManager.LoadAndSave, app.authenticateToken, noSurf, app.authenticate)
 		r.Get("/", app.Home)
-		r.Get("/snippet/view/{id}", app.SnippetView)
+		r.Get("/snippet/view/{slug}", app.SnippetView)
+		r.Get("/snippet/search", app.SnippetSearch)
+		r.Get("/snippet/raw/{slug}", app.SnippetRaw)
+		r.Get("/snippet/download/{slug}", app.SnippetDownload)
+		r.Get("/snippet/{slug}/history", app.SnippetHistory)
+		r.Get("/snippet/{slug}/diff", app.SnippetDiff)
 		r.Get("/about", app.AboutView)
 
 		// with auth middleware
 		r.With(app.requireAuth).Get("/snippet/create", app.SnippetCreate)
-		r.With(app.requireAuth).Post("/snippet/create", app.SnippetCreatePost)
+		r.With(app.requireAuth, limitSnippetCreate).Post("/snippet/create", app.SnippetCreatePost)
+		r.With(app.requireAuth).Get("/snippet/edit/{slug}", app.SnippetEdit)
+		r.With(app.requireAuth).Post("/snippet/edit/{slug}", app.SnippetEditPost)
+		r.With(app.requireAuth).Post("/snippet/delete/{slug}", app.SnippetDeletePost)
+		r.With(app.requireAuth).Post("/snippet/{slug}/restore/{revision}", app.SnippetRestorePost)
+	})
+
+	router.Route("/api/v1", func(r chi.Router) {
+		r.Use(app.sessionManager.LoadAndSave, app.authenticateToken, app.authenticate,
+			app.rateLimit("api", app.config.RateLimit.API, app.apiClientError))
+		r.NotFound(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			app.apiNotFound(w)
+		}))
+		r.MethodNotAllowed(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			app.apiClientError(w, http.StatusMethodNotAllowed)
+		}))
+
+		r.Get("/snippets", app.APISnippetList)
+		r.Get("/snippets/{slug}", app.APISnippetGet)
+
+		r.With(app.requireAPIAuth).Post("/snippets", app.APISnippetCreate)
+		r.With(app.requireAPIAuth).Put("/snippets/{slug}", app.APISnippetUpdate)
+		r.With(app.requireAPIAuth).Delete("/snippets/{slug}", app.APISnippetDelete)
 	})
 
 	return router
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"time"
+)
+
+// serve runs listen until it fails or signal is received on quit.
+// On signal app is marked as draining, so readiness fails while requests
+// are still served for drainDelay. Then server stops accepting
+// connections, waiting up to timeout for in-flight requests.
+// Second signal skips rest of drainDelay.
+func (app *App) serve(srv *http.Server, listen func() error, quit <-chan os.Signal, drainDelay, timeout time.Duration) error {
+	shutdownErr := make(chan error, 1)
+
+	go func() {
+		sig, ok := <-quit
+
+		if !ok {
+			return
+		}
+
+		app.logger.Info("draining connections", "signal", sig.String())
+		app.draining.Store(true)
+
+		select {
+		case <-time.After(drainDelay):
+		case <-quit:
+		}
+
+		app.logger.Info("shutting down")
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		shutdownErr <- srv.Shutdown(ctx)
+	}()
+
+	err := listen()
+
+	// ErrServerClosed means Shutdown was called and
+	// listen returned before in-flight requests finished
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return <-shutdownErr
+}
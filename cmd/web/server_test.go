@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"snippetbox/internal/tests"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// startSlowServer serves handler which blocks until release is closed
+// and returns channel receiving status of single request made to it
+func startSlowServer(t *testing.T, app *App, timeout time.Duration) (quit chan os.Signal, entered, release chan struct{}, status chan int, served chan error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	tests.NilError(t, err)
+
+	entered = make(chan struct{})
+	release = make(chan struct{})
+
+	srv := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(entered)
+			<-release
+			w.Write([]byte("OK"))
+		}),
+	}
+
+	quit = make(chan os.Signal, 1)
+	served = make(chan error, 1)
+
+	go func() {
+		served <- app.serve(srv, func() error { return srv.Serve(ln) }, quit, 0, timeout)
+	}()
+
+	status = make(chan int, 1)
+
+	go func() {
+		rs, err := http.Get("http://" + ln.Addr().String())
+
+		if err != nil {
+			status <- 0
+			return
+		}
+
+		rs.Body.Close()
+		status <- rs.StatusCode
+	}()
+
+	return quit, entered, release, status, served
+}
+
+func Test_serve(t *testing.T) {
+	t.Run("Drains in-flight requests", func(t *testing.T) {
+		app := newTestApp(t)
+		quit, entered, release, status, served := startSlowServer(t, app, 5*time.Second)
+
+		<-entered
+		quit <- syscall.SIGTERM
+
+		// readiness flips before in-flight request finishes
+		deadline := time.Now().Add(5 * time.Second)
+
+		for !app.draining.Load() {
+			if time.Now().After(deadline) {
+				t.Fatal("app not marked as draining")
+			}
+			time.Sleep(time.Millisecond)
+		}
+
+		close(release)
+
+		tests.Equal(t, <-status, http.StatusOK)
+		tests.NilError(t, <-served)
+	})
+
+	t.Run("Not ready during drain delay", func(t *testing.T) {
+		app := newTestApp(t)
+
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		tests.NilError(t, err)
+
+		srv := &http.Server{Handler: app.routes()}
+		quit := make(chan os.Signal, 1)
+		served := make(chan error, 1)
+
+		go func() {
+			served <- app.serve(srv, func() error { return srv.Serve(ln) }, quit, time.Minute, time.Second)
+		}()
+
+		readyz := func() int {
+			rs, err := http.Get("http://" + ln.Addr().String() + "/readyz")
+			tests.NilError(t, err)
+			rs.Body.Close()
+			return rs.StatusCode
+		}
+
+		tests.Equal(t, readyz(), http.StatusOK)
+
+		quit <- syscall.SIGTERM
+
+		// server still accepts connections, probes see it is going away
+		deadline := time.Now().Add(5 * time.Second)
+
+		for readyz() != http.StatusServiceUnavailable {
+			if time.Now().After(deadline) {
+				t.Fatal("readyz didnt fail during drain delay")
+			}
+			time.Sleep(time.Millisecond)
+		}
+
+		select {
+		case <-served:
+			t.Fatal("server stopped before drain delay passed")
+		default:
+		}
+
+		// second signal skips rest of delay
+		quit <- syscall.SIGTERM
+
+		tests.NilError(t, <-served)
+	})
+
+	t.Run("Shutdown timeout", func(t *testing.T) {
+		app := newTestApp(t)
+		quit, entered, release, _, served := startSlowServer(t, app, 10*time.Millisecond)
+		defer close(release)
+
+		<-entered
+		quit <- syscall.SIGINT
+
+		tests.Equal(t, errors.Is(<-served, context.DeadlineExceeded), true)
+	})
+
+	t.Run("Listen error", func(t *testing.T) {
+		app := newTestApp(t)
+		listenErr := errors.New("address already in use")
+
+		err := app.serve(&http.Server{}, func() error { return listenErr }, make(chan os.Signal), 0, time.Second)
+
+		tests.Equal(t, err, listenErr)
+		tests.Equal(t, app.draining.Load(), false)
+	})
+}
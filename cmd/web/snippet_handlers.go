@@ -2,59 +2,164 @@ package main
 
 import (
 	"errors"
-	"fmt"
 	"net/http"
+	"net/url"
+	"snippetbox/internal/highlight"
 	"snippetbox/internal/models"
+	"snippetbox/internal/templates"
 	"snippetbox/internal/validator"
 	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 )
 
 type SnippetCreateForm struct {
-	Title               string `form:"title"`
-	Content             string `form:"content"`
-	Expires             int    `form:"expires"`
-	validator.Validator `form:"-"`
+	Title               string `form:"title" json:"title"`
+	Content             string `form:"content" json:"content"`
+	Language            string `form:"language" json:"language"`
+	Visibility          string `form:"visibility" json:"visibility"`
+	Expires             int    `form:"expires" json:"expires"`
+	Fork                string `form:"fork" json:"-"`
+	validator.Validator `form:"-" json:"-"`
+}
+
+// days snippet can expire in, edits can also keep current expiry
+var (
+	createExpires = []int{1, 7, 365}
+	editExpires   = []int{models.KeepExpires, 1, 7, 365}
+)
+
+func (form *SnippetCreateForm) validate(expires []int) {
+	form.CheckField(validator.NotBlank(form.Title), "title", "This field cant be empty")
+	form.CheckField(validator.NotBlank(form.Content), "content", "This field cant be empty")
+	form.CheckField(validator.MaxChars(form.Title, 100), "title", "This field cant be more than 100 characters length")
+	form.CheckField(validator.PermittedValue(form.Expires, expires...), "expires", "This field must be 1, 7 or 365")
+	form.CheckField(validator.PermittedValue(form.Language, highlight.Languages...), "language", "This language is not supported")
+	form.CheckField(validator.PermittedValue(form.Visibility, models.Visibilities...), "visibility", "This field must be public, unlisted or private")
 }
 
 func (app *App) Home(w http.ResponseWriter, r *http.Request) {
 	snippets, err := app.snippets.Latest()
 
 	if err != nil {
-		app.serverError(w, err)
+		app.serverError(w, r, err)
 		return
 	}
 
 	data := app.newTemplateData(r)
 	data.Snippets = snippets
 
-	app.render(w, http.StatusOK, "home.tmpl.html", data)
+	app.render(w, r, http.StatusOK, "home.tmpl.html", data)
 }
 
 func (app *App) SnippetView(w http.ResponseWriter, r *http.Request) {
-	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	slug := chi.URLParam(r, "slug")
+
+	// links with sequential ids from before slugs were introduced
+	if id, err := strconv.Atoi(slug); err == nil {
+		app.redirectToSlug(w, r, id)
+		return
+	}
+
+	snippet, ok := app.viewableSnippet(w, r)
 
-	if err != nil || id < 1 {
+	if !ok {
+		return
+	}
+
+	forks, err := app.snippets.Forks(snippet.ID)
+
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	data.Forks = forks
+
+	app.render(w, r, http.StatusOK, "view.tmpl.html", data)
+}
+
+// viewableSnippet loads snippet from {slug} url param if current user can see it.
+// On failure response is already written.
+func (app *App) viewableSnippet(w http.ResponseWriter, r *http.Request) (*models.Snippet, bool) {
+	slug := chi.URLParam(r, "slug")
+
+	if !models.ValidSlug(slug) {
+		app.notFound(w)
+		return nil, false
+	}
+
+	// private snippets of other users are reported as missing, so their ids dont leak
+	snippet, err := app.snippets.GetBySlug(slug, app.authenticatedUserID(r))
+
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return nil, false
+	}
+
+	return snippet, true
+}
+
+// redirectToSlug permanently redirects old numeric snippet url to slug url.
+// Only public snippets are redirected, otherwise walking ids
+// would reveal slugs of unlisted snippets.
+func (app *App) redirectToSlug(w http.ResponseWriter, r *http.Request, id int) {
+	if id < 1 {
 		app.notFound(w)
 		return
 	}
 
-	snippet, err := app.snippets.Get(id)
+	userID := app.authenticatedUserID(r)
+
+	snippet, err := app.snippets.Get(id, userID)
 
 	if err != nil {
 		if errors.Is(err, models.ErrNoRecord) {
 			app.notFound(w)
 		} else {
-			app.serverError(w, err)
+			app.serverError(w, r, err)
 		}
 		return
 	}
 
+	if snippet.Visibility != models.VisibilityPublic && snippet.UserID != userID {
+		app.notFound(w)
+		return
+	}
+
+	http.Redirect(w, r, "/snippet/view/"+snippet.Slug, http.StatusMovedPermanently)
+}
+
+func (app *App) SnippetSearch(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	page := queryPage(r)
+
 	data := app.newTemplateData(r)
-	data.Snippet = snippet
+	data.Query = query
 
-	app.render(w, http.StatusOK, "view.tmpl.html", data)
+	if query == "" {
+		app.render(w, r, http.StatusOK, "search.tmpl.html", data)
+		return
+	}
+
+	snippets, total, err := app.snippets.Search(query, snippetsPageSize, (page-1)*snippetsPageSize)
+
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data.Snippets = snippets
+	data.Pagination = templates.NewPagination(page, snippetsPageSize, total, "/snippet/search", url.Values{"q": {query}})
+
+	app.render(w, r, http.StatusOK, "search.tmpl.html", data)
 }
 
 func (app *App) SnippetCreatePost(w http.ResponseWriter, r *http.Request) {
@@ -66,37 +171,233 @@ func (app *App) SnippetCreatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// validating
-	form.CheckField(validator.NotBlank(form.Title), "title", "This field cant be empty")
-	form.CheckField(validator.NotBlank(form.Content), "content", "This field cant be empty")
-	form.CheckField(validator.MaxChars(form.Title, 100), "title", "This field cant be more than 100 characters length")
-	form.CheckField(validator.PermittedValue(form.Expires, 1, 7, 365), "expires", "This field must be 1, 7 or 365")
+	form.validate(createExpires)
+
+	userID := app.authenticatedUserID(r)
+
+	var source *models.Snippet
+
+	if form.Fork != "" {
+		source, err = app.forkSource(form.Fork, userID)
+
+		if errors.Is(err, models.ErrNoRecord) {
+			form.AddNonFieldError("Snippet you are forking no longer exists")
+		} else if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+	}
 
 	if !form.Valid() {
 		data := app.newTemplateData(r)
+		data.Snippet = source
 		data.Form = form
-		app.render(w, http.StatusUnprocessableEntity, "create.tmpl.html", data)
+		app.render(w, r, http.StatusUnprocessableEntity, "create.tmpl.html", data)
 		return
 	}
 
-	id, err := app.snippets.Create(form.Title, form.Content, form.Expires)
+	forkedFrom := 0
+
+	if source != nil {
+		forkedFrom = source.ID
+	}
+
+	slug, err := app.snippets.Create(form.Title, form.Content, form.Language, form.Visibility, form.Expires, userID, forkedFrom)
 
 	if err != nil {
-		app.serverError(w, err)
+		app.serverError(w, r, err)
 		return
 	}
 
+	app.metrics.snippetsCreated.Inc()
 	app.sessionManager.Put(r.Context(), "flash", "Snippet succesfully created!")
 
-	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%d", id), http.StatusSeeOther)
+	http.Redirect(w, r, "/snippet/view/"+slug, http.StatusSeeOther)
 }
 
 func (app *App) SnippetCreate(w http.ResponseWriter, r *http.Request) {
 	data := app.newTemplateData(r)
 
+	form := SnippetCreateForm{
+		Language:   highlight.Plaintext,
+		Visibility: models.VisibilityPublic,
+		Expires:    365,
+	}
+
+	// ?fork={slug} prefills form with snippet being forked
+	if slug := r.URL.Query().Get("fork"); slug != "" {
+		source, err := app.forkSource(slug, app.authenticatedUserID(r))
+
+		if err != nil {
+			if errors.Is(err, models.ErrNoRecord) {
+				app.notFound(w)
+			} else {
+				app.serverError(w, r, err)
+			}
+			return
+		}
+
+		form.Title = source.Title
+		form.Content = source.Content
+		form.Language = source.Language
+		form.Visibility = source.Visibility
+		form.Fork = source.Slug
+		data.Snippet = source
+	}
+
+	data.Form = form
+
+	app.render(w, r, http.StatusOK, "create.tmpl.html", data)
+}
+
+// forkSource loads snippet user wants to fork,
+// anything user can view can be forked
+func (app *App) forkSource(slug string, userID int) (*models.Snippet, error) {
+	if !models.ValidSlug(slug) {
+		return nil, models.ErrNoRecord
+	}
+
+	return app.snippets.GetBySlug(slug, userID)
+}
+
+var errNotOwner = errors.New("snippet belongs to another user")
+
+// snippetForOwner loads snippet from {slug} url param and checks that
+// it belongs to current user. Expired snippets are included so they
+// can be renewed. Returns models.ErrNoRecord or errNotOwner on failure.
+func (app *App) snippetForOwner(r *http.Request) (*models.Snippet, error) {
+	slug := chi.URLParam(r, "slug")
+
+	if !models.ValidSlug(slug) {
+		return nil, models.ErrNoRecord
+	}
+
+	snippet, err := app.snippets.GetBySlugIncludingExpired(slug)
+
+	if err != nil {
+		return nil, err
+	}
+
+	userID := app.authenticatedUserID(r)
+
+	if !snippet.VisibleTo(userID) {
+		return nil, models.ErrNoRecord
+	}
+
+	if snippet.UserID != userID {
+		return nil, errNotOwner
+	}
+
+	return snippet, nil
+}
+
+// ownedSnippet is snippetForOwner for html handlers.
+// On failure response is already written.
+func (app *App) ownedSnippet(w http.ResponseWriter, r *http.Request) (*models.Snippet, bool) {
+	snippet, err := app.snippetForOwner(r)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrNoRecord):
+			app.notFound(w)
+		case errors.Is(err, errNotOwner):
+			app.clientError(w, http.StatusForbidden)
+		default:
+			app.serverError(w, r, err)
+		}
+		return nil, false
+	}
+
+	return snippet, true
+}
+
+func (app *App) SnippetEdit(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.ownedSnippet(w, r)
+
+	if !ok {
+		return
+	}
+
+	// saving fix of typo shouldnt move expiry, expired snippet is being renewed
+	expires := models.KeepExpires
+
+	if snippet.Expired() {
+		expires = 365
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
 	data.Form = SnippetCreateForm{
-		Expires: 365,
+		Title:      snippet.Title,
+		Content:    snippet.Content,
+		Language:   snippet.Language,
+		Visibility: snippet.Visibility,
+		Expires:    expires,
+	}
+
+	app.render(w, r, http.StatusOK, "edit.tmpl.html", data)
+}
+
+func (app *App) SnippetEditPost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.ownedSnippet(w, r)
+
+	if !ok {
+		return
 	}
 
-	app.render(w, http.StatusOK, "create.tmpl.html", data)
+	var form SnippetCreateForm
+	err := app.DecodePostForm(r, &form)
+
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form.validate(editExpires)
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Snippet = snippet
+		data.Form = form
+		app.render(w, r, http.StatusUnprocessableEntity, "edit.tmpl.html", data)
+		return
+	}
+
+	err = app.snippets.Update(snippet.ID, form.Title, form.Content, form.Language, form.Visibility, form.Expires, app.authenticatedUserID(r))
+
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Snippet succesfully updated!")
+
+	http.Redirect(w, r, "/snippet/view/"+snippet.Slug, http.StatusSeeOther)
+}
+
+func (app *App) SnippetDeletePost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.ownedSnippet(w, r)
+
+	if !ok {
+		return
+	}
+
+	err := app.snippets.Delete(snippet.ID)
+
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Snippet succesfully deleted!")
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
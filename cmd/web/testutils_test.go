@@ -4,16 +4,18 @@ import (
 	"bytes"
 	"html"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/http/cookiejar"
 	"net/http/httptest"
 	"net/url"
 	"regexp"
+	"strings"
 	"testing"
 	"time"
 
 	"snippetbox/internal/models/mocks"
+	"snippetbox/internal/signer"
 	"snippetbox/internal/templates"
 
 	"github.com/alexedwards/scs/v2"
@@ -24,6 +26,11 @@ type testServer struct {
 	*httptest.Server
 }
 
+const (
+	testSecretKey     = "test-secret-key-test-secret-key-"
+	testEncryptionKey = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+)
+
 var csrfTokenMock = regexp.MustCompile(`<input type='hidden' name='csrf_token' value='(.+)'>`)
 
 // loggers needed for middlewares
@@ -41,14 +48,38 @@ func newTestApp(t *testing.T) *App {
 	sessionManager.Lifetime = 12 * time.Hour
 	sessionManager.Cookie.Secure = true
 
+	sgn, err := signer.New(testSecretKey)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := defaultConfig()
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	metricsAllow, err := parseTrustedProxies(cfg.MetricsAllow)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	return &App{
-		errLogger:      log.New(io.Discard, "", 0),
-		infoLogger:     log.New(io.Discard, "", 0),
+		config:         cfg,
+		logger:         logger,
+		db:             &mocks.DB{},
+		metrics:        newMetrics(),
 		users:          &mocks.UserModel{},
 		snippets:       &mocks.SnippetModel{},
+		tokens:         &mocks.TokenModel{},
+		resets:         &mocks.PasswordResetModel{},
+		twoFactor:      &mocks.TwoFactorModel{},
+		throttle:       newLoginThrottle(cfg, mocks.NewLoginAttempts(), logger),
+		metricsAllow:   metricsAllow,
 		templateCache:  templateCache,
 		formDecoder:    formDecoder,
 		sessionManager: sessionManager,
+		mailer:         &mocks.Mailer{},
+		signer:         sgn,
 	}
 }
 
@@ -124,3 +155,46 @@ func (ts *testServer) postForm(t *testing.T, url string, form url.Values) (int,
 
 	return rs.StatusCode, rs.Header, string(body)
 }
+
+// login as mock user and return session csrf token
+func (ts *testServer) login(t *testing.T) string {
+	_, _, body := ts.get(t, "/user/login")
+
+	csrfToken := extractCsrfToken(t, body)
+
+	form := url.Values{}
+	form.Add("email", "user@test.com")
+	form.Add("password", "password")
+	form.Add("csrf_token", csrfToken)
+
+	ts.postForm(t, "/user/login", form)
+
+	return csrfToken
+}
+
+// mock json request
+func (ts *testServer) doJSON(t *testing.T, method, url, body string) (int, http.Header, string) {
+	req, err := http.NewRequest(method, ts.URL+url, strings.NewReader(body))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	rs, err := ts.Client().Do(req)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer rs.Body.Close()
+
+	respBody, err := io.ReadAll(rs.Body)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return rs.StatusCode, rs.Header, string(respBody)
+}
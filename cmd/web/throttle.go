@@ -0,0 +1,142 @@
+package main
+
+import (
+	"log/slog"
+	"net"
+	"net/http"
+	"snippetbox/internal/models"
+	"strings"
+	"time"
+)
+
+// loginThrottle slows down password guessing. Failures are counted per
+// account and per client IP, each failure after freeAttempts doubles wait
+// before next attempt, and after lockoutAfter failures key is locked out.
+// Failures older than lockout are forgotten.
+type loginThrottle struct {
+	attempts     models.LoginAttemptRepo
+	logger       *slog.Logger
+	freeAttempts int
+	backoff      time.Duration
+	lockoutAfter int
+	// IP is shared by everyone behind same NAT, so it gets more attempts
+	ipLockoutAfter int
+	lockout        time.Duration
+	now            func() time.Time
+}
+
+func newLoginThrottle(cfg *config, attempts models.LoginAttemptRepo, logger *slog.Logger) *loginThrottle {
+	return &loginThrottle{
+		attempts:       attempts,
+		logger:         logger,
+		freeAttempts:   cfg.Login.FreeAttempts,
+		backoff:        cfg.Login.Backoff,
+		lockoutAfter:   cfg.Login.LockoutAfter,
+		ipLockoutAfter: cfg.Login.IPLockoutAfter,
+		lockout:        cfg.Login.Lockout,
+		now:            time.Now,
+	}
+}
+
+type throttleKey struct {
+	kind         string
+	value        string
+	lockoutAfter int
+}
+
+func (k throttleKey) String() string {
+	return k.kind + ":" + k.value
+}
+
+func (t *loginThrottle) keys(email, ip string) []throttleKey {
+	return []throttleKey{
+		{kind: "account", value: strings.ToLower(email), lockoutAfter: t.lockoutAfter},
+		{kind: "ip", value: ip, lockoutAfter: t.ipLockoutAfter},
+	}
+}
+
+// delay returns how long to wait after last of failures
+func (t *loginThrottle) delay(failures, lockoutAfter int) time.Duration {
+	if failures >= lockoutAfter {
+		return t.lockout
+	}
+
+	if failures < t.freeAttempts {
+		return 0
+	}
+
+	exp := failures - t.freeAttempts
+	delay := t.backoff << exp
+
+	// shift overflowed or grew past lockout
+	if exp >= 63 || delay <= 0 || delay > t.lockout {
+		return t.lockout
+	}
+
+	return delay
+}
+
+// wait returns how long client has to wait before it can try to login
+func (t *loginThrottle) wait(email, ip string) (time.Duration, error) {
+	now := t.now()
+
+	var wait time.Duration
+
+	for _, key := range t.keys(email, ip) {
+		failures, last, err := t.attempts.Failures(key.String(), now.Add(-t.lockout))
+
+		if err != nil {
+			return 0, err
+		}
+
+		if w := last.Add(t.delay(failures, key.lockoutAfter)).Sub(now); w > wait {
+			wait = w
+		}
+	}
+
+	return wait, nil
+}
+
+// fail records failed login, lockouts are logged as security events
+func (t *loginThrottle) fail(email, ip string) error {
+	now := t.now()
+
+	for _, key := range t.keys(email, ip) {
+		failures, err := t.attempts.Fail(key.String(), now, now.Add(-t.lockout))
+
+		if err != nil {
+			return err
+		}
+
+		// each failure gets own count, so only one of concurrent
+		// failures crosses limit and lockout is logged once
+		if previous := failures - 1; previous < key.lockoutAfter && failures >= key.lockoutAfter {
+			t.logger.Warn("login lockout",
+				"event", "security",
+				"key", key.kind,
+				key.kind, key.value,
+				"failures", failures,
+				"until", now.Add(t.lockout),
+			)
+		}
+	}
+
+	return nil
+}
+
+// succeed forgets failures of account. Failures of IP are kept,
+// otherwise attacker could clear them by logging in to own account.
+func (t *loginThrottle) succeed(email string) error {
+	return t.attempts.Reset(t.keys(email, "")[0].String())
+}
+
+// clientIP returns IP request came from
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"snippetbox/internal/models/mocks"
+	"snippetbox/internal/tests"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestThrottle(logs *bytes.Buffer) (*loginThrottle, *time.Time) {
+	cfg := defaultConfig()
+	cfg.Login.FreeAttempts = 2
+	cfg.Login.Backoff = time.Second
+	cfg.Login.LockoutAfter = 5
+	cfg.Login.IPLockoutAfter = 8
+	cfg.Login.Lockout = 10 * time.Second
+
+	throttle := newLoginThrottle(cfg, mocks.NewLoginAttempts(), slog.New(slog.NewTextHandler(logs, nil)))
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	throttle.now = func() time.Time { return now }
+
+	return throttle, &now
+}
+
+func Test_loginThrottleDelay(t *testing.T) {
+	throttle, _ := newTestThrottle(&bytes.Buffer{})
+
+	testCases := []struct {
+		failures int
+		expDelay time.Duration
+	}{
+		{failures: 0, expDelay: 0},
+		{failures: 1, expDelay: 0},
+		{failures: 2, expDelay: time.Second},
+		{failures: 3, expDelay: 2 * time.Second},
+		{failures: 4, expDelay: 4 * time.Second},
+		{failures: 5, expDelay: 10 * time.Second},
+		{failures: 100, expDelay: 10 * time.Second},
+	}
+
+	for _, tt := range testCases {
+		tests.Equal(t, throttle.delay(tt.failures, 5), tt.expDelay)
+	}
+
+	// backoff is capped by lockout before lockoutAfter is reached
+	tests.Equal(t, throttle.delay(90, 100), 10*time.Second)
+}
+
+func Test_loginThrottleLockout(t *testing.T) {
+	var logs bytes.Buffer
+	throttle, now := newTestThrottle(&logs)
+
+	for i := 0; i < 5; i++ {
+		tests.NilError(t, throttle.fail("User@Test.com", "10.0.0.1"))
+	}
+
+	// other IP is locked out of same account, email case doesnt matter
+	wait, err := throttle.wait("user@test.com", "10.0.0.2")
+	tests.NilError(t, err)
+	tests.Equal(t, wait, 10*time.Second)
+
+	// other account from same IP only has to wait
+	wait, err = throttle.wait("other@test.com", "10.0.0.1")
+	tests.NilError(t, err)
+	tests.Equal(t, wait, 8*time.Second)
+
+	tests.StringContains(t, logs.String(), "login lockout")
+	tests.StringContains(t, logs.String(), "event=security")
+	tests.StringContains(t, logs.String(), "account=user@test.com")
+
+	*now = now.Add(10 * time.Second)
+
+	wait, err = throttle.wait("user@test.com", "10.0.0.1")
+	tests.NilError(t, err)
+	tests.Equal(t, wait, time.Duration(0))
+}
+
+func Test_loginThrottleConcurrentLockout(t *testing.T) {
+	var logs bytes.Buffer
+	throttle, _ := newTestThrottle(&logs)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			tests.NilError(t, throttle.fail("user@test.com", "10.0.0.1"))
+		}()
+	}
+
+	wg.Wait()
+
+	// each lockout is logged exactly once
+	tests.Equal(t, strings.Count(logs.String(), "key=account"), 1)
+	tests.Equal(t, strings.Count(logs.String(), "key=ip"), 1)
+}
+
+func Test_loginThrottleSucceed(t *testing.T) {
+	throttle, _ := newTestThrottle(&bytes.Buffer{})
+
+	for i := 0; i < 4; i++ {
+		tests.NilError(t, throttle.fail("user@test.com", "10.0.0.1"))
+	}
+
+	tests.NilError(t, throttle.succeed("user@test.com"))
+
+	wait, err := throttle.wait("user@test.com", "10.0.0.2")
+	tests.NilError(t, err)
+	tests.Equal(t, wait, time.Duration(0))
+
+	// failures of IP are kept
+	wait, err = throttle.wait("user@test.com", "10.0.0.1")
+	tests.NilError(t, err)
+	tests.Equal(t, wait, 4*time.Second)
+}
+
+func Test_UserLoginThrottled(t *testing.T) {
+	app := newTestApp(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	_, _, body := ts.get(t, "/user/login")
+	csrfToken := extractCsrfToken(t, body)
+
+	login := func(password string) (int, string) {
+		form := url.Values{}
+		form.Add("email", "user@test.com")
+		form.Add("password", password)
+		form.Add("csrf_token", csrfToken)
+
+		code, _, body := ts.postForm(t, "/user/login", form)
+
+		return code, body
+	}
+
+	for i := 0; i < app.config.Login.FreeAttempts; i++ {
+		code, _ := login("wrong password")
+		tests.Equal(t, code, http.StatusUnprocessableEntity)
+	}
+
+	code, body := login("wrong password")
+	tests.Equal(t, code, http.StatusTooManyRequests)
+	tests.StringContains(t, body, loginThrottledMessage)
+
+	// correct password is rejected too, so guesses cant be confirmed
+	code, body = login("password")
+	tests.Equal(t, code, http.StatusTooManyRequests)
+	tests.StringContains(t, body, loginThrottledMessage)
+
+	code, _, _ = ts.get(t, "/snippet/create")
+	tests.Equal(t, code, http.StatusSeeOther)
+}
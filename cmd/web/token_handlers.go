@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"snippetbox/internal/models"
+	"snippetbox/internal/templates"
+	"snippetbox/internal/validator"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type TokenCreateForm struct {
+	Name                string `form:"name"`
+	validator.Validator `form:"-"`
+}
+
+func (app *App) AccountTokens(w http.ResponseWriter, r *http.Request) {
+	data, err := app.tokensTemplateData(r)
+
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data.Form = TokenCreateForm{}
+
+	app.render(w, r, http.StatusOK, "tokens.tmpl.html", data)
+}
+
+func (app *App) AccountTokenCreatePost(w http.ResponseWriter, r *http.Request) {
+	// token shouldnt be able to issue new tokens
+	if isTokenAuthenticated(r) {
+		app.clientError(w, http.StatusForbidden)
+		return
+	}
+
+	var form TokenCreateForm
+	err := app.DecodePostForm(r, &form)
+
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	// validating
+	form.CheckField(validator.NotBlank(form.Name), "name", "This field cant be empty")
+	form.CheckField(validator.MaxChars(form.Name, 100), "name", "This field cant be more than 100 characters length")
+
+	if !form.Valid() {
+		data, err := app.tokensTemplateData(r)
+
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		data.Form = form
+		app.render(w, r, http.StatusUnprocessableEntity, "tokens.tmpl.html", data)
+		return
+	}
+
+	token, err := app.tokens.Create(app.authenticatedUserID(r), form.Name)
+
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// token is shown only once, so page is rendered instead of redirect
+	data, err := app.tokensTemplateData(r)
+
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data.Form = TokenCreateForm{}
+	data.NewToken = token
+
+	app.render(w, r, http.StatusCreated, "tokens.tmpl.html", data)
+}
+
+func (app *App) AccountTokenRevokePost(w http.ResponseWriter, r *http.Request) {
+	if isTokenAuthenticated(r) {
+		app.clientError(w, http.StatusForbidden)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+
+	err = app.tokens.Delete(id, app.authenticatedUserID(r))
+
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Token has been revoked.")
+	http.Redirect(w, r, "/account/tokens", http.StatusSeeOther)
+}
+
+func (app *App) tokensTemplateData(r *http.Request) (*templates.TemplateData, error) {
+	tokens, err := app.tokens.ByUser(app.authenticatedUserID(r))
+
+	if err != nil {
+		return nil, err
+	}
+
+	data := app.newTemplateData(r)
+	data.Tokens = tokens
+
+	return data, nil
+}
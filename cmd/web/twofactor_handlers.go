@@ -0,0 +1,312 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"snippetbox/internal/models"
+	"snippetbox/internal/totp"
+	"snippetbox/internal/validator"
+	"time"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// issuer shown in authenticator apps
+const totpIssuer = "Snippetbox"
+
+// how long second login step can be completed after password was accepted
+const twoFactorTimeout = 5 * time.Minute
+
+// pixels, big enough to be scanned from screen
+const qrCodeSize = 256
+
+type TwoFactorForm struct {
+	Code                string `form:"code"`
+	validator.Validator `form:"-"`
+}
+
+// startTwoFactorLogin remembers user whose password was accepted,
+// user is logged in only after code is verified
+func (app *App) startTwoFactorLogin(w http.ResponseWriter, r *http.Request, id int) {
+	err := app.sessionManager.RenewToken(r.Context())
+
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "twoFactorUserID", id)
+	app.sessionManager.Put(r.Context(), "twoFactorStarted", time.Now().Unix())
+
+	http.Redirect(w, r, "/user/login/2fa", http.StatusSeeOther)
+}
+
+// twoFactorUserID returns user waiting for second login step,
+// 0 if there is none or it took too long
+func (app *App) twoFactorUserID(r *http.Request) int {
+	started := time.Unix(app.sessionManager.GetInt64(r.Context(), "twoFactorStarted"), 0)
+
+	if time.Since(started) > twoFactorTimeout {
+		return 0
+	}
+
+	return app.sessionManager.GetInt(r.Context(), "twoFactorUserID")
+}
+
+func (app *App) UserLoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	if app.twoFactorUserID(r) == 0 {
+		http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Form = TwoFactorForm{}
+	app.render(w, r, http.StatusOK, "login_2fa.tmpl.html", data)
+}
+
+func (app *App) UserLoginTwoFactorPost(w http.ResponseWriter, r *http.Request) {
+	id := app.twoFactorUserID(r)
+
+	if id == 0 {
+		app.sessionManager.Put(r.Context(), "flash", "Login has expired, please try again.")
+		http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+		return
+	}
+
+	var form TwoFactorForm
+	err := app.DecodePostForm(r, &form)
+
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	// codes are guessed easier than passwords, so they count as login failures
+	user, err := app.users.Get(id)
+
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	ip := clientIP(r)
+
+	throttled, err := app.loginThrottled(user.Email, ip)
+
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if throttled {
+		form.AddNonFieldError(loginThrottledMessage)
+
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, r, http.StatusTooManyRequests, "login_2fa.tmpl.html", data)
+		return
+	}
+
+	form.CheckField(validator.NotBlank(form.Code), "code", "This field cant be empty")
+
+	if form.Valid() {
+		err = app.twoFactor.Verify(id, form.Code)
+
+		if errors.Is(err, models.ErrInvalidCredentials) {
+			app.metrics.logins.WithLabelValues(loginFailure).Inc()
+
+			if err := app.throttle.fail(user.Email, ip); err != nil {
+				app.serverError(w, r, err)
+				return
+			}
+
+			form.AddFieldError("code", "Code is incorrect")
+		} else if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+	}
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, r, http.StatusUnprocessableEntity, "login_2fa.tmpl.html", data)
+		return
+	}
+
+	err = app.throttle.succeed(user.Email)
+
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.sessionManager.Remove(r.Context(), "twoFactorUserID")
+	app.sessionManager.Remove(r.Context(), "twoFactorStarted")
+
+	app.logIn(w, r, id)
+}
+
+func (app *App) AccountTwoFactor(w http.ResponseWriter, r *http.Request) {
+	// leaked token mustnt be enough to read secret or take over two-factor
+	if isTokenAuthenticated(r) {
+		app.clientError(w, http.StatusForbidden)
+		return
+	}
+
+	app.renderTwoFactor(w, r, http.StatusOK, TwoFactorForm{}, nil)
+}
+
+// renderTwoFactor shows setup with QR code when two-factor is disabled,
+// otherwise form disabling it. Recovery codes are shown right after enabling.
+func (app *App) renderTwoFactor(w http.ResponseWriter, r *http.Request, status int, form TwoFactorForm, recoveryCodes []string) {
+	id := app.authenticatedUserID(r)
+
+	enabled, err := app.twoFactor.Enabled(id)
+
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Form = form
+	data.TwoFactorEnabled = enabled
+	data.RecoveryCodes = recoveryCodes
+
+	if !enabled {
+		data.TOTPSecret, err = app.twoFactor.Setup(id)
+
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+	}
+
+	app.render(w, r, status, "twofactor.tmpl.html", data)
+}
+
+// AccountTwoFactorQR serves QR code of secret waiting for confirmation
+func (app *App) AccountTwoFactorQR(w http.ResponseWriter, r *http.Request) {
+	if isTokenAuthenticated(r) {
+		app.clientError(w, http.StatusForbidden)
+		return
+	}
+
+	id := app.authenticatedUserID(r)
+
+	user, err := app.users.Get(id)
+
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	secret, err := app.twoFactor.Setup(id)
+
+	if err != nil {
+		if errors.Is(err, models.ErrTwoFactorEnabled) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	png, err := qrcode.Encode(totp.URL(totpIssuer, user.Email, secret), qrcode.Medium, qrCodeSize)
+
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(png)
+}
+
+func (app *App) AccountTwoFactorEnablePost(w http.ResponseWriter, r *http.Request) {
+	if isTokenAuthenticated(r) {
+		app.clientError(w, http.StatusForbidden)
+		return
+	}
+
+	var form TwoFactorForm
+	err := app.DecodePostForm(r, &form)
+
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form.CheckField(validator.NotBlank(form.Code), "code", "This field cant be empty")
+
+	var codes []string
+
+	if form.Valid() {
+		codes, err = app.twoFactor.Confirm(app.authenticatedUserID(r), form.Code)
+
+		switch {
+		case errors.Is(err, models.ErrInvalidCredentials):
+			form.AddFieldError("code", "Code is incorrect, check time on your device")
+		case errors.Is(err, models.ErrTwoFactorEnabled):
+			http.Redirect(w, r, "/account/2fa", http.StatusSeeOther)
+			return
+		case err != nil:
+			app.serverError(w, r, err)
+			return
+		}
+	}
+
+	if !form.Valid() {
+		app.renderTwoFactor(w, r, http.StatusUnprocessableEntity, form, nil)
+		return
+	}
+
+	// codes arent stored in plaintext, so page is rendered instead of redirect
+	app.renderTwoFactor(w, r, http.StatusOK, TwoFactorForm{}, codes)
+}
+
+func (app *App) AccountTwoFactorDisablePost(w http.ResponseWriter, r *http.Request) {
+	if isTokenAuthenticated(r) {
+		app.clientError(w, http.StatusForbidden)
+		return
+	}
+
+	var form TwoFactorForm
+	err := app.DecodePostForm(r, &form)
+
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	id := app.authenticatedUserID(r)
+
+	form.CheckField(validator.NotBlank(form.Code), "code", "This field cant be empty")
+
+	if form.Valid() {
+		err = app.twoFactor.Verify(id, form.Code)
+
+		if errors.Is(err, models.ErrInvalidCredentials) {
+			form.AddFieldError("code", "Code is incorrect")
+		} else if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+	}
+
+	if !form.Valid() {
+		app.renderTwoFactor(w, r, http.StatusUnprocessableEntity, form, nil)
+		return
+	}
+
+	err = app.twoFactor.Disable(id)
+
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Two-factor authentication has been disabled.")
+	http.Redirect(w, r, "/account/2fa", http.StatusSeeOther)
+}
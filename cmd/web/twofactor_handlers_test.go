@@ -0,0 +1,234 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"snippetbox/internal/models/mocks"
+	"snippetbox/internal/tests"
+	"strings"
+	"testing"
+)
+
+// loginTwoFactor submits password of user with two-factor enabled
+// and returns csrf token of second step form
+func (ts *testServer) loginTwoFactor(t *testing.T) string {
+	_, _, body := ts.get(t, "/user/login")
+
+	form := url.Values{}
+	form.Add("email", mocks.TwoFactorEmail)
+	form.Add("password", "password")
+	form.Add("csrf_token", extractCsrfToken(t, body))
+
+	code, header, _ := ts.postForm(t, "/user/login", form)
+	tests.Equal(t, code, http.StatusSeeOther)
+	tests.Equal(t, header.Get("Location"), "/user/login/2fa")
+
+	code, _, body = ts.get(t, "/user/login/2fa")
+	tests.Equal(t, code, http.StatusOK)
+
+	return extractCsrfToken(t, body)
+}
+
+func Test_UserLoginTwoFactor(t *testing.T) {
+	app := newTestApp(t)
+
+	t.Run("Without password", func(t *testing.T) {
+		ts := newTestServer(t, app.routes())
+		defer ts.Close()
+
+		code, header, _ := ts.get(t, "/user/login/2fa")
+
+		tests.Equal(t, code, http.StatusSeeOther)
+		tests.Equal(t, header.Get("Location"), "/user/login")
+	})
+
+	testCases := []struct {
+		name        string
+		code        string
+		expCode     int
+		expLocation string
+	}{
+		{
+			name:    "Empty code",
+			expCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:    "Wrong code",
+			code:    "000000",
+			expCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:        "TOTP code",
+			code:        mocks.ValidTOTPCode,
+			expCode:     http.StatusSeeOther,
+			expLocation: "/account/view",
+		},
+		{
+			name:        "Recovery code",
+			code:        mocks.ValidRecoveryCode,
+			expCode:     http.StatusSeeOther,
+			expLocation: "/account/view",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, app.routes())
+			defer ts.Close()
+
+			csrfToken := ts.loginTwoFactor(t)
+
+			// password alone doesnt log in, page is opened after login
+			code, _, _ := ts.get(t, "/account/view")
+			tests.Equal(t, code, http.StatusSeeOther)
+
+			form := url.Values{}
+			form.Add("code", tt.code)
+			form.Add("csrf_token", csrfToken)
+
+			code, header, _ := ts.postForm(t, "/user/login/2fa", form)
+
+			tests.Equal(t, code, tt.expCode)
+			tests.Equal(t, header.Get("Location"), tt.expLocation)
+
+			expAccountCode := http.StatusSeeOther
+
+			if tt.expLocation != "" {
+				expAccountCode = http.StatusOK
+			}
+
+			code, _, _ = ts.get(t, "/account/view")
+			tests.Equal(t, code, expAccountCode)
+		})
+	}
+}
+
+func Test_AccountTwoFactor(t *testing.T) {
+	app := newTestApp(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	csrfToken := ts.login(t)
+
+	code, _, body := ts.get(t, "/account/2fa")
+	tests.Equal(t, code, http.StatusOK)
+	tests.StringContains(t, body, mocks.TOTPSecret)
+	tests.StringContains(t, body, "<img class='qr' src='/account/2fa/qr'")
+
+	code, header, body := ts.get(t, "/account/2fa/qr")
+	tests.Equal(t, code, http.StatusOK)
+	tests.Equal(t, header.Get("Content-Type"), "image/png")
+	tests.StringContains(t, body, "\x89PNG")
+
+	testCases := []struct {
+		name    string
+		code    string
+		expCode int
+		expBody string
+	}{
+		{
+			name:    "Wrong code",
+			code:    "000000",
+			expCode: http.StatusUnprocessableEntity,
+			expBody: "Code is incorrect",
+		},
+		{
+			name:    "Valid code",
+			code:    mocks.ValidTOTPCode,
+			expCode: http.StatusOK,
+			expBody: mocks.ValidRecoveryCode,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("code", tt.code)
+			form.Add("csrf_token", csrfToken)
+
+			code, _, body := ts.postForm(t, "/account/2fa/enable", form)
+
+			tests.Equal(t, code, tt.expCode)
+			tests.StringContains(t, body, tt.expBody)
+		})
+	}
+}
+
+func Test_AccountTwoFactorDisable(t *testing.T) {
+	app := newTestApp(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	csrfToken := ts.loginTwoFactor(t)
+
+	form := url.Values{}
+	form.Add("code", mocks.ValidTOTPCode)
+	form.Add("csrf_token", csrfToken)
+	ts.postForm(t, "/user/login/2fa", form)
+
+	code, _, body := ts.get(t, "/account/2fa")
+	tests.Equal(t, code, http.StatusOK)
+	tests.StringContains(t, body, "<form action='/account/2fa/disable' method='POST' novalidate>")
+
+	code, _, _ = ts.get(t, "/account/2fa/qr")
+	tests.Equal(t, code, http.StatusNotFound)
+
+	csrfToken = extractCsrfToken(t, body)
+
+	form = url.Values{}
+	form.Add("code", "000000")
+	form.Add("csrf_token", csrfToken)
+
+	code, _, _ = ts.postForm(t, "/account/2fa/disable", form)
+	tests.Equal(t, code, http.StatusUnprocessableEntity)
+
+	form.Set("code", mocks.ValidRecoveryCode)
+
+	code, header, _ := ts.postForm(t, "/account/2fa/disable", form)
+	tests.Equal(t, code, http.StatusSeeOther)
+	tests.Equal(t, header.Get("Location"), "/account/2fa")
+}
+
+func Test_AccountTokenForbidden(t *testing.T) {
+	app := newTestApp(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	testCases := []struct {
+		method string
+		url    string
+	}{
+		{method: http.MethodGet, url: "/account/2fa"},
+		{method: http.MethodGet, url: "/account/2fa/qr"},
+		{method: http.MethodPost, url: "/account/2fa/enable"},
+		{method: http.MethodPost, url: "/account/2fa/disable"},
+		{method: http.MethodGet, url: "/account/password/update"},
+		{method: http.MethodPost, url: "/account/password/update"},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.method+" "+tt.url, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("code", mocks.ValidTOTPCode)
+
+			req, err := http.NewRequest(tt.method, ts.URL+tt.url, strings.NewReader(form.Encode()))
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.Header.Set("Authorization", "Bearer "+mocks.ValidToken)
+
+			rs, err := ts.Client().Do(req)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer rs.Body.Close()
+
+			tests.Equal(t, rs.StatusCode, http.StatusForbidden)
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"snippetbox/internal/mailer"
+	"snippetbox/internal/models"
+	"snippetbox/internal/validator"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// signer purpose of email verification links
+const verifyPurpose = "verify-email"
+
+const verifyEmailBody = `Hi %s,
+
+please verify your email address by opening link below:
+
+%s
+
+Link is valid for %s. If you didn't sign up for Snippetbox, ignore this email.
+`
+
+type VerifyResendForm struct {
+	Email               string `form:"email"`
+	validator.Validator `form:"-"`
+}
+
+// sendVerificationEmail mails link which verifies current email of user.
+// Email is part of token, so link stops working if email changes.
+func (app *App) sendVerificationEmail(user *models.User) error {
+	ttl := app.config.Mail.VerifyTTL
+	token := app.signer.Sign(verifyPurpose, fmt.Sprintf("%d:%s", user.Id, user.Email), time.Now().Add(ttl))
+	link := app.config.BaseURL + "/user/verify?token=" + url.QueryEscape(token)
+
+	return app.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your Snippetbox email",
+		Body:    fmt.Sprintf(verifyEmailBody, user.Name, link, ttl),
+	})
+}
+
+func (app *App) UserVerify(w http.ResponseWriter, r *http.Request) {
+	invalid := func() {
+		app.sessionManager.Put(r.Context(), "flash", "Verification link is invalid or has expired, request new one below.")
+		http.Redirect(w, r, "/user/verify/resend", http.StatusSeeOther)
+	}
+
+	subject, err := app.signer.Verify(verifyPurpose, r.URL.Query().Get("token"), time.Now())
+
+	if err != nil {
+		invalid()
+		return
+	}
+
+	idStr, email, _ := strings.Cut(subject, ":")
+	id, err := strconv.Atoi(idStr)
+
+	if err != nil {
+		invalid()
+		return
+	}
+
+	err = app.users.VerifyEmail(id, email)
+
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			invalid()
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "Your email has been verified, you can login now.")
+	http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+}
+
+func (app *App) UserVerifyResend(w http.ResponseWriter, r *http.Request) {
+	data := app.newTemplateData(r)
+	data.Form = VerifyResendForm{}
+	app.render(w, r, http.StatusOK, "verify_resend.tmpl.html", data)
+}
+
+func (app *App) UserVerifyResendPost(w http.ResponseWriter, r *http.Request) {
+	var form VerifyResendForm
+	err := app.DecodePostForm(r, &form)
+
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form.CheckField(validator.NotBlank(form.Email), "email", "This field cant be empty")
+	form.CheckField(validator.Matches(form.Email, validator.EmailRegex), "email", "This field must be valid email")
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Form = form
+		app.render(w, r, http.StatusUnprocessableEntity, "verify_resend.tmpl.html", data)
+		return
+	}
+
+	user, err := app.users.GetByEmail(form.Email)
+
+	if err != nil && !errors.Is(err, models.ErrNoRecord) {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// sent in background and same response whether account exists or not,
+	// so emails cant be probed by content or timing of response
+	if err == nil && !user.EmailVerified {
+		app.background(func() error {
+			return app.sendVerificationEmail(user)
+		})
+	}
+
+	app.sessionManager.Put(r.Context(), "flash", "If that email belongs to unverified account, we've sent new verification link.")
+	http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+}
@@ -1,16 +1,33 @@
 module snippetbox
 
-go 1.20
+go 1.21
 
 require (
+	github.com/alecthomas/chroma/v2 v2.7.0
 	github.com/alexedwards/scs/mysqlstore v0.0.0-20230327161757-10d4299e3b24
 	github.com/alexedwards/scs/v2 v2.5.1
 	github.com/go-chi/chi/v5 v5.0.8
 	github.com/go-playground/form/v4 v4.2.0
 	github.com/go-sql-driver/mysql v1.7.1
+	github.com/justinas/nosurf v1.1.1
+	github.com/prometheus/client_golang v1.15.1
+	github.com/skip2/go-qrcode v0.0.0-20200617195104-da1b6568686e
+	golang.org/x/crypto v0.8.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
-	github.com/justinas/nosurf v1.1.1 // indirect
-	golang.org/x/crypto v0.8.0 // indirect
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.2.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/dlclark/regexp2 v1.4.0 // indirect
+	github.com/golang/protobuf v1.5.3 // indirect
+	github.com/kr/text v0.2.0 // indirect
+	github.com/matttproud/golang_protobuf_extensions v1.0.4 // indirect
+	github.com/prometheus/client_model v0.3.0 // indirect
+	github.com/prometheus/common v0.42.0 // indirect
+	github.com/prometheus/procfs v0.9.0 // indirect
+	github.com/rogpeppe/go-internal v1.9.0 // indirect
+	golang.org/x/sys v0.7.0 // indirect
+	google.golang.org/protobuf v1.30.0 // indirect
 )
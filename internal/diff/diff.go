@@ -0,0 +1,264 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Kind byte
+
+const (
+	Equal  Kind = ' '
+	Delete Kind = '-'
+	Insert Kind = '+'
+)
+
+type Line struct {
+	Kind Kind
+	Text string
+}
+
+// Hunk is group of changed lines with surrounding context,
+// line numbers are 1-based like in unified diff format
+type Hunk struct {
+	FromLine  int
+	FromCount int
+	ToLine    int
+	ToCount   int
+	Lines     []Line
+}
+
+// above this number of compared line pairs, changed region
+// is reported as fully replaced instead of computing LCS
+const maxCells = 4_000_000
+
+// Header returns hunk range line, e.g. "@@ -1,3 +1,4 @@"
+func (h Hunk) Header() string {
+	return fmt.Sprintf("@@ -%s +%s @@", hunkRange(h.FromLine, h.FromCount), hunkRange(h.ToLine, h.ToCount))
+}
+
+func (k Kind) String() string {
+	switch k {
+	case Delete:
+		return "delete"
+	case Insert:
+		return "insert"
+	default:
+		return "equal"
+	}
+}
+
+// Lines returns line by line difference between a and b
+func Lines(a, b string) []Line {
+	from := splitLines(a)
+	to := splitLines(b)
+
+	// common prefix and suffix dont need LCS table
+	prefix := 0
+	for prefix < len(from) && prefix < len(to) && from[prefix] == to[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(from)-prefix && suffix < len(to)-prefix &&
+		from[len(from)-1-suffix] == to[len(to)-1-suffix] {
+		suffix++
+	}
+
+	lines := make([]Line, 0, len(from)+len(to))
+
+	for _, text := range from[:prefix] {
+		lines = append(lines, Line{Equal, text})
+	}
+
+	lines = append(lines, middle(from[prefix:len(from)-suffix], to[prefix:len(to)-suffix])...)
+
+	for _, text := range from[len(from)-suffix:] {
+		lines = append(lines, Line{Equal, text})
+	}
+
+	return lines
+}
+
+// Unified groups difference between a and b into hunks
+// with given number of context lines
+func Unified(a, b string, context int) []Hunk {
+	lines := Lines(a, b)
+	hunks := []Hunk{}
+
+	// line numbers before each line
+	fromLine, toLine := make([]int, len(lines)+1), make([]int, len(lines)+1)
+	fromLine[0], toLine[0] = 1, 1
+
+	for i, l := range lines {
+		fromLine[i+1], toLine[i+1] = fromLine[i], toLine[i]
+
+		if l.Kind != Insert {
+			fromLine[i+1]++
+		}
+
+		if l.Kind != Delete {
+			toLine[i+1]++
+		}
+	}
+
+	i := 0
+	for i < len(lines) {
+		if lines[i].Kind == Equal {
+			i++
+			continue
+		}
+
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+
+		// extend hunk while next change is within context
+		end := i
+		for end < len(lines) {
+			if lines[end].Kind != Equal {
+				end++
+				continue
+			}
+
+			next := end
+			for next < len(lines) && lines[next].Kind == Equal {
+				next++
+			}
+
+			if next == len(lines) || next-end > 2*context {
+				end += context
+				if end > len(lines) {
+					end = len(lines)
+				}
+				break
+			}
+
+			end = next
+		}
+
+		hunk := Hunk{
+			FromLine: fromLine[start],
+			ToLine:   toLine[start],
+			Lines:    lines[start:end],
+		}
+
+		for _, l := range hunk.Lines {
+			if l.Kind != Insert {
+				hunk.FromCount++
+			}
+			if l.Kind != Delete {
+				hunk.ToCount++
+			}
+		}
+
+		hunks = append(hunks, hunk)
+		i = end
+	}
+
+	return hunks
+}
+
+// Format writes hunks as unified diff text
+func Format(fromName, toName string, hunks []Hunk) string {
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+
+	for _, h := range hunks {
+		sb.WriteString(h.Header())
+		sb.WriteByte('\n')
+
+		for _, l := range h.Lines {
+			sb.WriteByte(byte(l.Kind))
+			sb.WriteString(l.Text)
+			sb.WriteByte('\n')
+		}
+	}
+
+	return sb.String()
+}
+
+// middle diffs region without common prefix and suffix using LCS
+func middle(from, to []string) []Line {
+	lines := make([]Line, 0, len(from)+len(to))
+
+	if len(from)*len(to) > maxCells || len(from) == 0 || len(to) == 0 {
+		for _, text := range from {
+			lines = append(lines, Line{Delete, text})
+		}
+		for _, text := range to {
+			lines = append(lines, Line{Insert, text})
+		}
+		return lines
+	}
+
+	// lcs[i][j] is LCS length of from[i:] and to[j:]
+	width := len(to) + 1
+	lcs := make([]int32, (len(from)+1)*width)
+
+	for i := len(from) - 1; i >= 0; i-- {
+		for j := len(to) - 1; j >= 0; j-- {
+			if from[i] == to[j] {
+				lcs[i*width+j] = lcs[(i+1)*width+j+1] + 1
+			} else if lcs[(i+1)*width+j] >= lcs[i*width+j+1] {
+				lcs[i*width+j] = lcs[(i+1)*width+j]
+			} else {
+				lcs[i*width+j] = lcs[i*width+j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(from) && j < len(to) {
+		switch {
+		case from[i] == to[j]:
+			lines = append(lines, Line{Equal, from[i]})
+			i++
+			j++
+		case lcs[(i+1)*width+j] >= lcs[i*width+j+1]:
+			lines = append(lines, Line{Delete, from[i]})
+			i++
+		default:
+			lines = append(lines, Line{Insert, to[j]})
+			j++
+		}
+	}
+
+	for ; i < len(from); i++ {
+		lines = append(lines, Line{Delete, from[i]})
+	}
+	for ; j < len(to); j++ {
+		lines = append(lines, Line{Insert, to[j]})
+	}
+
+	return lines
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+func hunkRange(line, count int) string {
+	// empty range points at line before it
+	if count == 0 {
+		return fmt.Sprintf("%d,0", line-1)
+	}
+
+	if count == 1 {
+		return fmt.Sprintf("%d", line)
+	}
+
+	return fmt.Sprintf("%d,%d", line, count)
+}
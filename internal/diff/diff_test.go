@@ -0,0 +1,77 @@
+package diff
+
+import (
+	"snippetbox/internal/tests"
+	"testing"
+)
+
+func Test_Unified(t *testing.T) {
+	testCases := []struct {
+		name string
+		from string
+		to   string
+		want string
+	}{
+		{
+			name: "Equal",
+			from: "a\nb\nc",
+			to:   "a\nb\nc",
+			want: "",
+		},
+		{
+			name: "Changed line",
+			from: "a\nb\nc",
+			to:   "a\nB\nc",
+			want: "--- 1\n+++ 2\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+		},
+		{
+			name: "Added lines",
+			from: "a",
+			to:   "a\nb\nc",
+			want: "--- 1\n+++ 2\n@@ -1 +1,3 @@\n a\n+b\n+c\n",
+		},
+		{
+			name: "From empty",
+			from: "",
+			to:   "a\nb",
+			want: "--- 1\n+++ 2\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name: "To empty",
+			from: "a",
+			to:   "",
+			want: "--- 1\n+++ 2\n@@ -1 +0,0 @@\n-a\n",
+		},
+		{
+			name: "Separate hunks",
+			from: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10",
+			to:   "one\n2\n3\n4\n5\n6\n7\n8\n9\nten",
+			want: "--- 1\n+++ 2\n@@ -1,2 +1,2 @@\n-1\n+one\n 2\n@@ -9,2 +9,2 @@\n 9\n-10\n+ten\n",
+		},
+		{
+			name: "Windows line endings",
+			from: "a\r\nb\r\n",
+			to:   "a\nb\n",
+			want: "",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Format("1", "2", Unified(tt.from, tt.to, 1))
+
+			tests.Equal(t, got, tt.want)
+		})
+	}
+}
+
+func Test_LinesMinimal(t *testing.T) {
+	lines := Lines("a\nb\nc\nd", "a\nc\nd\ne")
+
+	kinds := ""
+	for _, l := range lines {
+		kinds += string(l.Kind)
+	}
+
+	tests.Equal(t, kinds, " -  +")
+}
@@ -0,0 +1,78 @@
+// Package encrypt protects secrets stored in database with AES-256-GCM.
+package encrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// KeyLength is length of key in bytes, key is configured as hex
+const KeyLength = 32
+
+var ErrDecrypt = errors.New("encrypt: message authentication failed")
+
+type Box struct {
+	aead cipher.AEAD
+}
+
+// New returns Box using hex encoded key
+func New(hexKey string) (*Box, error) {
+	key, err := hex.DecodeString(hexKey)
+
+	if err != nil {
+		return nil, fmt.Errorf("encrypt: key must be hex: %w", err)
+	}
+
+	if len(key) != KeyLength {
+		return nil, fmt.Errorf("encrypt: key must be %d bytes, got %d", KeyLength, len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Box{aead: aead}, nil
+}
+
+// Seal encrypts plaintext with random nonce prepended to result.
+// Same context must be given to Open, it binds ciphertext to its owner,
+// so it cant be copied to other row.
+func (b *Box) Seal(plaintext, context []byte) ([]byte, error) {
+	nonce := make([]byte, b.aead.NonceSize(), b.aead.NonceSize()+len(plaintext)+b.aead.Overhead())
+
+	_, err := rand.Read(nonce)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return b.aead.Seal(nonce, nonce, plaintext, context), nil
+}
+
+func (b *Box) Open(ciphertext, context []byte) ([]byte, error) {
+	size := b.aead.NonceSize()
+
+	if len(ciphertext) < size {
+		return nil, ErrDecrypt
+	}
+
+	plaintext, err := b.aead.Open(nil, ciphertext[:size], ciphertext[size:], context)
+
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+
+	return plaintext, nil
+}
@@ -0,0 +1,67 @@
+package encrypt
+
+import (
+	"bytes"
+	"snippetbox/internal/tests"
+	"strings"
+	"testing"
+)
+
+var testKey = strings.Repeat("ab", KeyLength)
+
+func Test_New(t *testing.T) {
+	testCases := []struct {
+		name   string
+		key    string
+		expErr string
+	}{
+		{name: "Valid", key: testKey},
+		{name: "Not hex", key: strings.Repeat("zz", KeyLength), expErr: "encrypt: key must be hex"},
+		{name: "Too short", key: "abcd", expErr: "encrypt: key must be 32 bytes, got 2"},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.key)
+
+			if tt.expErr == "" {
+				tests.NilError(t, err)
+				return
+			}
+
+			if err == nil {
+				t.Fatal("expected error")
+			}
+
+			tests.StringContains(t, err.Error(), tt.expErr)
+		})
+	}
+}
+
+func Test_SealOpen(t *testing.T) {
+	box, err := New(testKey)
+	tests.NilError(t, err)
+
+	plaintext := []byte("JBSWY3DPEHPK3PXP")
+
+	sealed, err := box.Seal(plaintext, []byte("user:1"))
+	tests.NilError(t, err)
+
+	if bytes.Contains(sealed, plaintext) {
+		t.Fatal("plaintext visible in ciphertext")
+	}
+
+	opened, err := box.Open(sealed, []byte("user:1"))
+	tests.NilError(t, err)
+	tests.Equal(t, string(opened), string(plaintext))
+
+	_, err = box.Open(sealed, []byte("user:2"))
+	tests.Equal(t, err, ErrDecrypt)
+
+	sealed[len(sealed)-1] ^= 1
+	_, err = box.Open(sealed, []byte("user:1"))
+	tests.Equal(t, err, ErrDecrypt)
+
+	_, err = box.Open([]byte("short"), nil)
+	tests.Equal(t, err, ErrDecrypt)
+}
@@ -0,0 +1,72 @@
+package highlight
+
+import (
+	"bytes"
+	"html/template"
+	"io"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+const Plaintext = "plaintext"
+
+// Languages snippets can be highlighted with
+var Languages = []string{Plaintext, "go", "sql", "yaml", "json", "bash", "javascript", "python", "html", "css"}
+
+// classes instead of inline styles,
+// so output is allowed by Content-Security-Policy
+var formatter = html.New(html.WithClasses(true), html.TabWidth(4))
+
+var style = styles.Get("github")
+
+// HTML returns code highlighted as escaped html
+func HTML(code, language string) (template.HTML, error) {
+	lexer := lexers.Get(language)
+
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+
+	iterator, err := chroma.Coalesce(lexer).Tokenise(nil, code)
+
+	if err != nil {
+		return "", err
+	}
+
+	buf := new(bytes.Buffer)
+
+	err = formatter.Format(buf, style, iterator)
+
+	if err != nil {
+		return "", err
+	}
+
+	return template.HTML(buf.String()), nil
+}
+
+// CSS writes stylesheet with classes used by HTML
+func CSS(w io.Writer) error {
+	return formatter.WriteCSS(w, style)
+}
+
+// Extension returns file name extension for language, like ".go".
+// Unknown languages are treated as plain text.
+func Extension(language string) string {
+	lexer := lexers.Get(language)
+
+	if lexer != nil {
+		for _, pattern := range lexer.Config().Filenames {
+			ext := strings.TrimPrefix(pattern, "*")
+
+			if ext != pattern && !strings.ContainsAny(ext, "*?[") {
+				return ext
+			}
+		}
+	}
+
+	return ".txt"
+}
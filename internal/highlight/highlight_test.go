@@ -0,0 +1,77 @@
+package highlight
+
+import (
+	"snippetbox/internal/tests"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/chroma/v2/lexers"
+)
+
+func Test_HTML(t *testing.T) {
+	testCases := []struct {
+		name     string
+		code     string
+		language string
+		want     string
+	}{
+		{
+			name:     "Go keyword",
+			code:     "package main",
+			language: "go",
+			want:     `<span class="kn">package</span>`,
+		},
+		{
+			name:     "Escapes html",
+			code:     "<script>alert(1)</script>",
+			language: Plaintext,
+			want:     "&lt;script&gt;alert(1)&lt;/script&gt;",
+		},
+		{
+			name:     "Unknown language",
+			code:     "plain text",
+			language: "brainfuck2",
+			want:     "plain text",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			html, err := HTML(tt.code, tt.language)
+
+			tests.NilError(t, err)
+			tests.StringContains(t, string(html), tt.want)
+
+			// inline styles are blocked by Content-Security-Policy
+			if strings.Contains(string(html), "style=") {
+				t.Errorf("got inline style in %s", html)
+			}
+		})
+	}
+}
+
+func Test_LanguagesHaveLexers(t *testing.T) {
+	for _, language := range Languages {
+		if lexers.Get(language) == nil {
+			t.Errorf("no lexer for %s", language)
+		}
+	}
+}
+
+func Test_Extension(t *testing.T) {
+	testCases := []struct {
+		language string
+		want     string
+	}{
+		{language: Plaintext, want: ".txt"},
+		{language: "go", want: ".go"},
+		{language: "bash", want: ".sh"},
+		{language: "brainfuck2", want: ".txt"},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.language, func(t *testing.T) {
+			tests.Equal(t, Extension(tt.language), tt.want)
+		})
+	}
+}
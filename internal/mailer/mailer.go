@@ -0,0 +1,120 @@
+// Package mailer sends plain text emails. SMTP delivers real mail,
+// Log and File let local development run without mail server.
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(msg Message) error
+}
+
+type SMTP struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+func (m *SMTP) Send(msg Message) error {
+	addr := net.JoinHostPort(m.Host, strconv.Itoa(m.Port))
+
+	var auth smtp.Auth
+
+	// PlainAuth refuses to send credentials over unencrypted connection,
+	// SendMail upgrades with STARTTLS when server supports it
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	// envelope needs bare addresses, From header may have display name
+	from, err := mail.ParseAddress(m.From)
+
+	if err != nil {
+		return err
+	}
+
+	to, err := mail.ParseAddress(msg.To)
+
+	if err != nil {
+		return err
+	}
+
+	return smtp.SendMail(addr, auth, from.Address, []string{to.Address}, format(m.From, msg, time.Now()))
+}
+
+// Log writes messages to logger. Bodies contain links with tokens,
+// so they are logged only when Body is set, meant for development.
+type Log struct {
+	Logger *slog.Logger
+	Body   bool
+}
+
+func (m *Log) Send(msg Message) error {
+	if !m.Body {
+		m.Logger.Info("email", "to", msg.To, "subject", msg.Subject)
+		return nil
+	}
+
+	m.Logger.Info("email", "to", msg.To, "subject", msg.Subject, "body", msg.Body)
+	return nil
+}
+
+// File writes every message to own .eml file in Dir
+type File struct {
+	Dir  string
+	From string
+}
+
+func (m *File) Send(msg Message) error {
+	now := time.Now()
+
+	err := os.MkdirAll(m.Dir, 0o700)
+
+	if err != nil {
+		return err
+	}
+
+	// nanoseconds keep names unique and sorted by time
+	name := filepath.Join(m.Dir, strconv.FormatInt(now.UnixNano(), 10)+".eml")
+
+	return os.WriteFile(name, format(m.From, msg, now), 0o600)
+}
+
+// format builds RFC 5322 message. Line breaks are removed from headers,
+// otherwise user input could inject own headers.
+func format(from string, msg Message, date time.Time) []byte {
+	header := strings.NewReplacer("\r", "", "\n", "")
+
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, "From: %s\r\n", header.Replace(from))
+	fmt.Fprintf(&b, "To: %s\r\n", header.Replace(msg.To))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", header.Replace(msg.Subject)))
+	fmt.Fprintf(&b, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n"))
+
+	return b.Bytes()
+}
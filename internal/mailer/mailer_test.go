@@ -0,0 +1,80 @@
+package mailer
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"snippetbox/internal/tests"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_format(t *testing.T) {
+	date := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	msg := Message{
+		To:      "user@test.com\r\nBcc: victim@test.com",
+		Subject: "Verify your email",
+		Body:    "Hello,\nclick link.",
+	}
+
+	expected := "From: Snippetbox <no-reply@test.com>\r\n" +
+		"To: user@test.comBcc: victim@test.com\r\n" +
+		"Subject: Verify your email\r\n" +
+		"Date: Mon, 01 May 2023 10:00:00 +0000\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n" +
+		"\r\n" +
+		"Hello,\r\nclick link."
+
+	tests.Equal(t, string(format("Snippetbox <no-reply@test.com>", msg, date)), expected)
+}
+
+func Test_File(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	m := &File{Dir: dir, From: "no-reply@test.com"}
+
+	err := m.Send(Message{To: "user@test.com", Subject: "Hi", Body: "Body"})
+	tests.NilError(t, err)
+
+	files, err := os.ReadDir(dir)
+	tests.NilError(t, err)
+	tests.Equal(t, len(files), 1)
+
+	content, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	tests.NilError(t, err)
+
+	tests.StringContains(t, string(content), "To: user@test.com\r\n")
+	tests.StringContains(t, string(content), "\r\n\r\nBody")
+}
+
+func Test_Log(t *testing.T) {
+	msg := Message{
+		To:      "user@test.com",
+		Subject: "Reset your password",
+		Body:    "https://localhost/user/password/reset/secret-token",
+	}
+
+	testCases := []struct {
+		name    string
+		body    bool
+		expBody bool
+	}{
+		{name: "Without body", body: false, expBody: false},
+		{name: "With body", body: true, expBody: true},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			m := &Log{Logger: slog.New(slog.NewTextHandler(&buf, nil)), Body: tt.body}
+
+			tests.NilError(t, m.Send(msg))
+
+			tests.StringContains(t, buf.String(), "subject=\"Reset your password\"")
+			tests.Equal(t, strings.Contains(buf.String(), "secret-token"), tt.expBody)
+		})
+	}
+}
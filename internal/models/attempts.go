@@ -0,0 +1,81 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// LoginAttemptRepo counts failed logins by key, like account or client IP.
+// Failures before since are forgotten, and deleted by Fail.
+type LoginAttemptRepo interface {
+	Failures(key string, since time.Time) (int, time.Time, error)
+	Fail(key string, at, since time.Time) (int, error)
+	Reset(key string) error
+}
+
+type LoginAttemptModel struct {
+	DB *sql.DB
+}
+
+// Failures returns number of failures and time of last one
+func (m *LoginAttemptModel) Failures(key string, since time.Time) (int, time.Time, error) {
+	var failures int
+	var last time.Time
+
+	query := `
+	SELECT failures, last_failure FROM login_attempts
+	WHERE attempt_key = ? AND last_failure >= ?
+	`
+	err := m.DB.QueryRow(query, key, since.UTC()).Scan(&failures, &last)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, time.Time{}, nil
+		} else {
+			return 0, time.Time{}, err
+		}
+	}
+
+	return failures, last, nil
+}
+
+// Fail records failure and returns number of failures including it.
+// Count is returned by same statement, so concurrent failures get different counts.
+func (m *LoginAttemptModel) Fail(key string, at, since time.Time) (int, error) {
+	// keys are chosen by clients, so forgotten ones are cleaned up here
+	_, err := m.DB.Exec(`DELETE FROM login_attempts WHERE last_failure < ?`, since.UTC())
+
+	if err != nil {
+		return 0, err
+	}
+
+	// failures is updated before last_failure, so it sees previous failure time.
+	// LAST_INSERT_ID(expr) makes new count available as insert id of result.
+	query := `
+	INSERT INTO login_attempts (attempt_key, failures, last_failure)
+	VALUES(?, LAST_INSERT_ID(1), ?)
+	ON DUPLICATE KEY UPDATE
+		failures = LAST_INSERT_ID(IF(last_failure < ?, 1, failures + 1)),
+		last_failure = ?
+	`
+	res, err := m.DB.Exec(query, key, at.UTC(), since.UTC(), at.UTC())
+
+	if err != nil {
+		return 0, err
+	}
+
+	failures, err := res.LastInsertId()
+
+	if err != nil {
+		return 0, err
+	}
+
+	return int(failures), nil
+}
+
+func (m *LoginAttemptModel) Reset(key string) error {
+	_, err := m.DB.Exec(`DELETE FROM login_attempts WHERE attempt_key = ?`, key)
+
+	return err
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"snippetbox/internal/tests"
+	"testing"
+	"time"
+)
+
+func Test_LoginAttemptModel(t *testing.T) {
+	if testing.Short() {
+		t.Skip("models: skipping integration test")
+	}
+
+	db := newTestDb(t)
+
+	model := LoginAttemptModel{DB: db}
+
+	start := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	window := time.Hour
+
+	for i := 1; i <= 3; i++ {
+		at := start.Add(time.Duration(i) * time.Minute)
+
+		failures, err := model.Fail("ip:10.0.0.1", at, at.Add(-window))
+		tests.NilError(t, err)
+		tests.Equal(t, failures, i)
+	}
+
+	failures, last, err := model.Failures("ip:10.0.0.1", start)
+	tests.NilError(t, err)
+	tests.Equal(t, failures, 3)
+	tests.Equal(t, last, start.Add(3*time.Minute))
+
+	_, err = model.Fail("ip:10.0.0.2", start, start.Add(-window))
+	tests.NilError(t, err)
+
+	// old failures are forgotten
+	later := start.Add(2 * window)
+
+	failures, _, err = model.Failures("ip:10.0.0.1", later.Add(-window))
+	tests.NilError(t, err)
+	tests.Equal(t, failures, 0)
+
+	failures, err = model.Fail("ip:10.0.0.1", later, later.Add(-window))
+	tests.NilError(t, err)
+	tests.Equal(t, failures, 1)
+
+	// and deleted by next failure
+	var rows int
+
+	err = db.QueryRow(`SELECT COUNT(*) FROM login_attempts WHERE attempt_key = ?`, "ip:10.0.0.2").Scan(&rows)
+	tests.NilError(t, err)
+	tests.Equal(t, rows, 0)
+
+	tests.NilError(t, model.Reset("ip:10.0.0.1"))
+
+	failures, _, err = model.Failures("ip:10.0.0.1", start)
+	tests.NilError(t, err)
+	tests.Equal(t, failures, 0)
+}
@@ -5,3 +5,5 @@ import "errors"
 var ErrNoRecord = errors.New("models: no matching record found")
 var ErrInvalidCredentials = errors.New("models: invalid credentials")
 var ErrDuplicateEmail = errors.New("models: duplicate email")
+var ErrEmailNotVerified = errors.New("models: email not verified")
+var ErrTwoFactorEnabled = errors.New("models: two-factor authentication already enabled")
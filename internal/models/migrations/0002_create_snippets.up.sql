@@ -0,0 +1,10 @@
+-- same schema as setup.sql used before migrations existed,
+-- IF NOT EXISTS lets databases created by it adopt migrations
+CREATE TABLE IF NOT EXISTS snippets (
+    id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    title VARCHAR(100) NOT NULL,
+    content TEXT NOT NULL,
+    created DATETIME NOT NULL,
+    expires DATETIME NOT NULL,
+    INDEX idx_snippets_created (created)
+);
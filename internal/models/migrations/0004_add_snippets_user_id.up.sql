@@ -0,0 +1,12 @@
+ALTER TABLE snippets ADD COLUMN user_id INTEGER AFTER expires;
+
+-- author of snippets created before ownership isnt known,
+-- they are given to oldest account, usually the one of site owner
+UPDATE snippets SET user_id = (SELECT MIN(id) FROM users);
+
+-- without any account nobody could own them
+DELETE FROM snippets WHERE user_id IS NULL;
+
+ALTER TABLE snippets MODIFY user_id INTEGER NOT NULL;
+
+ALTER TABLE snippets ADD CONSTRAINT fk_snippets_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
@@ -0,0 +1 @@
+ALTER TABLE snippets ADD COLUMN language VARCHAR(20) NOT NULL DEFAULT 'plaintext' AFTER content;
@@ -0,0 +1 @@
+ALTER TABLE snippets ADD COLUMN visibility ENUM('public', 'unlisted', 'private') NOT NULL DEFAULT 'public' AFTER language;
@@ -0,0 +1,28 @@
+ALTER TABLE snippets
+    ADD COLUMN slug CHAR(12) CHARACTER SET ascii COLLATE ascii_bin AFTER id,
+    ADD COLUMN slug_seed BINARY(12);
+
+-- existing snippets get random slugs like generated ones, first char
+-- is always letter so slug cant be mistaken for numeric id
+UPDATE snippets SET slug_seed = RANDOM_BYTES(12);
+
+UPDATE snippets SET slug = CONCAT(
+    SUBSTRING('ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz', ORD(SUBSTRING(slug_seed, 1, 1)) % 52 + 1, 1),
+    SUBSTRING('0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz', ORD(SUBSTRING(slug_seed, 2, 1)) % 62 + 1, 1),
+    SUBSTRING('0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz', ORD(SUBSTRING(slug_seed, 3, 1)) % 62 + 1, 1),
+    SUBSTRING('0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz', ORD(SUBSTRING(slug_seed, 4, 1)) % 62 + 1, 1),
+    SUBSTRING('0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz', ORD(SUBSTRING(slug_seed, 5, 1)) % 62 + 1, 1),
+    SUBSTRING('0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz', ORD(SUBSTRING(slug_seed, 6, 1)) % 62 + 1, 1),
+    SUBSTRING('0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz', ORD(SUBSTRING(slug_seed, 7, 1)) % 62 + 1, 1),
+    SUBSTRING('0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz', ORD(SUBSTRING(slug_seed, 8, 1)) % 62 + 1, 1),
+    SUBSTRING('0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz', ORD(SUBSTRING(slug_seed, 9, 1)) % 62 + 1, 1),
+    SUBSTRING('0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz', ORD(SUBSTRING(slug_seed, 10, 1)) % 62 + 1, 1),
+    SUBSTRING('0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz', ORD(SUBSTRING(slug_seed, 11, 1)) % 62 + 1, 1),
+    SUBSTRING('0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz', ORD(SUBSTRING(slug_seed, 12, 1)) % 62 + 1, 1)
+);
+
+ALTER TABLE snippets
+    DROP COLUMN slug_seed,
+    MODIFY slug CHAR(12) CHARACTER SET ascii COLLATE ascii_bin NOT NULL;
+
+ALTER TABLE snippets ADD CONSTRAINT snippets_uc_slug UNIQUE (slug);
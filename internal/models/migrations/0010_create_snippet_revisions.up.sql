@@ -0,0 +1,20 @@
+CREATE TABLE snippet_revisions (
+    id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    snippet_id INTEGER NOT NULL,
+    revision INTEGER NOT NULL,
+    title VARCHAR(100) NOT NULL,
+    content TEXT NOT NULL,
+    language VARCHAR(20) NOT NULL,
+    user_id INTEGER NOT NULL,
+    created DATETIME NOT NULL
+);
+
+ALTER TABLE snippet_revisions ADD CONSTRAINT snippet_revisions_uc_revision UNIQUE (snippet_id, revision);
+
+ALTER TABLE snippet_revisions ADD CONSTRAINT fk_snippet_revisions_snippet_id FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE;
+
+ALTER TABLE snippet_revisions ADD CONSTRAINT fk_snippet_revisions_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+-- existing snippets start history with their current content
+INSERT INTO snippet_revisions (snippet_id, revision, title, content, language, user_id, created)
+SELECT id, 1, title, content, language, user_id, created FROM snippets;
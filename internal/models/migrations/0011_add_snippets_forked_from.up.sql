@@ -0,0 +1,3 @@
+ALTER TABLE snippets ADD COLUMN forked_from INTEGER AFTER user_id;
+
+ALTER TABLE snippets ADD CONSTRAINT fk_snippets_forked_from FOREIGN KEY (forked_from) REFERENCES snippets(id) ON DELETE SET NULL;
@@ -0,0 +1,6 @@
+ALTER TABLE snippets ADD COLUMN updated DATETIME AFTER created;
+
+-- snippets werent edited before column existed
+UPDATE snippets SET updated = created;
+
+ALTER TABLE snippets MODIFY updated DATETIME NOT NULL;
@@ -0,0 +1,8 @@
+CREATE TABLE password_resets (
+    hash BINARY(32) NOT NULL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    created DATETIME NOT NULL,
+    expires DATETIME NOT NULL
+);
+
+ALTER TABLE password_resets ADD CONSTRAINT fk_password_resets_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
@@ -0,0 +1,19 @@
+CREATE TABLE totp_secrets (
+    user_id INTEGER NOT NULL PRIMARY KEY,
+    secret VARBINARY(255) NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    last_step BIGINT NOT NULL DEFAULT 0,
+    created DATETIME NOT NULL
+);
+
+ALTER TABLE totp_secrets ADD CONSTRAINT fk_totp_secrets_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+CREATE TABLE recovery_codes (
+    id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    user_id INTEGER NOT NULL,
+    hash BINARY(32) NOT NULL
+);
+
+ALTER TABLE recovery_codes ADD CONSTRAINT recovery_codes_uc_hash UNIQUE (user_id, hash);
+
+ALTER TABLE recovery_codes ADD CONSTRAINT fk_recovery_codes_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
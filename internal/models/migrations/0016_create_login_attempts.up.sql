@@ -0,0 +1,5 @@
+CREATE TABLE login_attempts (
+    attempt_key VARCHAR(320) CHARACTER SET ascii COLLATE ascii_bin NOT NULL PRIMARY KEY,
+    failures INTEGER NOT NULL,
+    last_failure DATETIME(6) NOT NULL
+);
@@ -0,0 +1 @@
+DROP INDEX idx_login_attempts_last_failure ON login_attempts;
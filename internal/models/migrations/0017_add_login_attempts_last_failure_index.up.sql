@@ -0,0 +1 @@
+CREATE INDEX idx_login_attempts_last_failure ON login_attempts (last_failure);
@@ -0,0 +1,353 @@
+// Package migrations applies versioned schema changes embedded in binary.
+//
+// Every migration is pair of files NNNN_name.up.sql and NNNN_name.down.sql.
+// Applied versions are recorded in schema_migrations table.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+var fileRegex = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// advisory lock held while migrating, so concurrent deploys dont race
+const (
+	lockName    = "snippetbox_migrations"
+	lockTimeout = 30 // seconds
+)
+
+var ErrLocked = errors.New("migrations: another migration is in progress")
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status of migration in database, Applied is zero for pending migration
+type Status struct {
+	Migration
+	Applied time.Time
+}
+
+// All returns embedded migrations ordered by version
+func All() ([]*Migration, error) {
+	return load(files)
+}
+
+func load(fsys fs.FS) ([]*Migration, error) {
+	names, err := fs.Glob(fsys, "*.sql")
+
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+
+	for _, name := range names {
+		match := fileRegex.FindStringSubmatch(name)
+
+		if match == nil {
+			return nil, fmt.Errorf("migrations: invalid file name %s", name)
+		}
+
+		version, _ := strconv.Atoi(match[1])
+
+		script, err := fs.ReadFile(fsys, name)
+
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migrations: version %d used by %s and %s", version, m.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			m.Up = string(script)
+		} else {
+			m.Down = string(script)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migrations: %04d_%s needs both up and down script", m.Version, m.Name)
+		}
+
+		migrations = append(migrations, m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Up applies all pending migrations and returns them
+func Up(db *sql.DB) ([]*Migration, error) {
+	all, err := All()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []*Migration
+
+	err = withLock(db, func(conn *sql.Conn) error {
+		done, err := appliedVersions(conn)
+
+		if err != nil {
+			return err
+		}
+
+		for _, m := range all {
+			if _, ok := done[m.Version]; ok {
+				continue
+			}
+
+			// MySQL commits DDL implicitly, so failed migration isnt rolled back
+			// and has to be fixed by hand. Keep migrations small.
+			err := execScript(conn, m.Up)
+
+			if err != nil {
+				return fmt.Errorf("migrations: %04d_%s up: %w", m.Version, m.Name, err)
+			}
+
+			_, err = conn.ExecContext(context.Background(),
+				`INSERT INTO schema_migrations (version, name, applied) VALUES (?, ?, UTC_TIMESTAMP())`,
+				m.Version, m.Name)
+
+			if err != nil {
+				return err
+			}
+
+			applied = append(applied, m)
+		}
+
+		return nil
+	})
+
+	return applied, err
+}
+
+// Down reverts up to steps latest applied migrations and returns them
+func Down(db *sql.DB, steps int) ([]*Migration, error) {
+	all, err := All()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var reverted []*Migration
+
+	err = withLock(db, func(conn *sql.Conn) error {
+		done, err := appliedVersions(conn)
+
+		if err != nil {
+			return err
+		}
+
+		for i := len(all) - 1; i >= 0 && len(reverted) < steps; i-- {
+			m := all[i]
+
+			if _, ok := done[m.Version]; !ok {
+				continue
+			}
+
+			err := execScript(conn, m.Down)
+
+			if err != nil {
+				return fmt.Errorf("migrations: %04d_%s down: %w", m.Version, m.Name, err)
+			}
+
+			_, err = conn.ExecContext(context.Background(),
+				`DELETE FROM schema_migrations WHERE version = ?`, m.Version)
+
+			if err != nil {
+				return err
+			}
+
+			reverted = append(reverted, m)
+		}
+
+		return nil
+	})
+
+	return reverted, err
+}
+
+// Statuses returns all embedded migrations with time they were applied
+func Statuses(db *sql.DB) ([]*Status, error) {
+	all, err := All()
+
+	if err != nil {
+		return nil, err
+	}
+
+	// reading doesnt need lock
+	conn, err := db.Conn(context.Background())
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.Close()
+
+	done, err := appliedVersions(conn)
+
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]*Status, 0, len(all))
+
+	for _, m := range all {
+		statuses = append(statuses, &Status{Migration: *m, Applied: done[m.Version]})
+	}
+
+	return statuses, nil
+}
+
+// withLock runs fn on single connection holding migrations lock,
+// MySQL locks belong to connection so pool cant be used
+func withLock(db *sql.DB, fn func(conn *sql.Conn) error) error {
+	ctx := context.Background()
+
+	conn, err := db.Conn(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	var locked sql.NullInt64
+
+	err = conn.QueryRowContext(ctx, `SELECT GET_LOCK(?, ?)`, lockName, lockTimeout).Scan(&locked)
+
+	if err != nil {
+		return err
+	}
+
+	if locked.Int64 != 1 {
+		return ErrLocked
+	}
+
+	defer conn.QueryRowContext(ctx, `SELECT RELEASE_LOCK(?)`, lockName).Scan(&locked)
+
+	return fn(conn)
+}
+
+// appliedVersions creates schema_migrations table if needed
+// and returns applied versions with time they were applied
+func appliedVersions(conn *sql.Conn) (map[int]time.Time, error) {
+	ctx := context.Background()
+
+	_, err := conn.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER NOT NULL PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		applied DATETIME NOT NULL
+	)`)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := conn.QueryContext(ctx, `SELECT version, applied FROM schema_migrations`)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	versions := map[int]time.Time{}
+
+	for rows.Next() {
+		var version int
+		var applied time.Time
+
+		err := rows.Scan(&version, &applied)
+
+		if err != nil {
+			return nil, err
+		}
+
+		versions[version] = applied
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+// execScript runs statements of script one by one,
+// so DSN doesnt need multiStatements option
+func execScript(conn *sql.Conn, script string) error {
+	for _, statement := range Statements(script) {
+		_, err := conn.ExecContext(context.Background(), statement)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Statements splits script on semicolons ending line.
+// Semicolons inside statements are kept, but statement must not end
+// line with semicolon inside string literal.
+func Statements(script string) []string {
+	var statements []string
+	var current strings.Builder
+
+	for _, line := range strings.SplitAfter(script, "\n") {
+		current.WriteString(line)
+
+		if strings.HasSuffix(strings.TrimSpace(line), ";") {
+			statements = appendStatement(statements, current.String())
+			current.Reset()
+		}
+	}
+
+	return appendStatement(statements, current.String())
+}
+
+func appendStatement(statements []string, statement string) []string {
+	statement = strings.TrimSuffix(strings.TrimSpace(statement), ";")
+
+	if statement == "" {
+		return statements
+	}
+
+	return append(statements, statement)
+}
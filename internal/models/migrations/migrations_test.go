@@ -0,0 +1,113 @@
+package migrations
+
+import (
+	"reflect"
+	"snippetbox/internal/tests"
+	"testing"
+	"testing/fstest"
+)
+
+func Test_All(t *testing.T) {
+	all, err := All()
+	tests.NilError(t, err)
+
+	if len(all) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+
+	for i, m := range all {
+		tests.Equal(t, m.Version, i+1)
+
+		if len(Statements(m.Up)) == 0 || len(Statements(m.Down)) == 0 {
+			t.Errorf("migration %d has empty script", m.Version)
+		}
+	}
+
+	tests.Equal(t, all[0].Name, "create_users")
+}
+
+func Test_load(t *testing.T) {
+	file := func(s string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte(s)}
+	}
+
+	testCases := []struct {
+		name   string
+		fsys   fstest.MapFS
+		expErr string
+	}{
+		{
+			name: "Ordered by version",
+			fsys: fstest.MapFS{
+				"0010_b.up.sql":   file("B"),
+				"0010_b.down.sql": file("B"),
+				"0002_a.up.sql":   file("A"),
+				"0002_a.down.sql": file("A"),
+			},
+		},
+		{
+			name: "Invalid name",
+			fsys: fstest.MapFS{
+				"0001_a.sql": file("A"),
+			},
+			expErr: "migrations: invalid file name 0001_a.sql",
+		},
+		{
+			name: "Missing down",
+			fsys: fstest.MapFS{
+				"0001_a.up.sql": file("A"),
+			},
+			expErr: "migrations: 0001_a needs both up and down script",
+		},
+		{
+			name: "Duplicate version",
+			fsys: fstest.MapFS{
+				"0001_a.up.sql":   file("A"),
+				"0001_a.down.sql": file("A"),
+				"0001_b.up.sql":   file("B"),
+			},
+			expErr: "migrations: version 1 used by a and b",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			migrations, err := load(tt.fsys)
+
+			if tt.expErr != "" {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+
+				tests.Equal(t, err.Error(), tt.expErr)
+				return
+			}
+
+			tests.NilError(t, err)
+			tests.Equal(t, len(migrations), 2)
+			tests.Equal(t, migrations[0].Name, "a")
+			tests.Equal(t, migrations[1].Name, "b")
+		})
+	}
+}
+
+func Test_Statements(t *testing.T) {
+	script := `CREATE TABLE a (
+    id INTEGER
+);
+
+-- trailing statement without semicolon
+INSERT INTO a VALUES (';'), (1)
+`
+
+	want := []string{
+		"CREATE TABLE a (\n    id INTEGER\n)",
+		"-- trailing statement without semicolon\nINSERT INTO a VALUES (';'), (1)",
+	}
+
+	got := Statements(script)
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q; want %q", got, want)
+	}
+}
@@ -0,0 +1,58 @@
+package mocks
+
+import (
+	"sync"
+	"time"
+)
+
+// LoginAttempts keeps failures in memory, behaves like MySQL store
+type LoginAttempts struct {
+	mu       sync.Mutex
+	failures map[string]int
+	last     map[string]time.Time
+}
+
+func NewLoginAttempts() *LoginAttempts {
+	return &LoginAttempts{
+		failures: map[string]int{},
+		last:     map[string]time.Time{},
+	}
+}
+
+func (m *LoginAttempts) Failures(key string, since time.Time) (int, time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.last[key].Before(since) {
+		return 0, time.Time{}, nil
+	}
+
+	return m.failures[key], m.last[key], nil
+}
+
+func (m *LoginAttempts) Fail(key string, at, since time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for k, last := range m.last {
+		if last.Before(since) {
+			delete(m.failures, k)
+			delete(m.last, k)
+		}
+	}
+
+	m.failures[key]++
+	m.last[key] = at
+
+	return m.failures[key], nil
+}
+
+func (m *LoginAttempts) Reset(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.failures, key)
+	delete(m.last, key)
+
+	return nil
+}
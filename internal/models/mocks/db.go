@@ -0,0 +1,13 @@
+package mocks
+
+import "context"
+
+// DB stands in for *sql.DB in readiness checks,
+// PingContext returns Err
+type DB struct {
+	Err error
+}
+
+func (m *DB) PingContext(ctx context.Context) error {
+	return m.Err
+}
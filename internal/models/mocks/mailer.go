@@ -0,0 +1,32 @@
+package mocks
+
+import (
+	"snippetbox/internal/mailer"
+	"sync"
+)
+
+// Mailer keeps sent messages instead of sending them
+type Mailer struct {
+	mu   sync.Mutex
+	sent []mailer.Message
+}
+
+func (m *Mailer) Send(msg mailer.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// Last returns last sent message, ok is false if nothing was sent
+func (m *Mailer) Last() (mailer.Message, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.sent) == 0 {
+		return mailer.Message{}, false
+	}
+
+	return m.sent[len(m.sent)-1], true
+}
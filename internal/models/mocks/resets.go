@@ -0,0 +1,27 @@
+package mocks
+
+import (
+	"snippetbox/internal/models"
+	"time"
+)
+
+// reset token accepted by mock for user 1
+const ValidResetToken = "valid-reset-token"
+
+type PasswordResetModel struct{}
+
+func (m *PasswordResetModel) Create(userID int, ttl time.Duration) (string, error) {
+	return ValidResetToken, nil
+}
+
+func (m *PasswordResetModel) Valid(plaintext string) (bool, error) {
+	return plaintext == ValidResetToken, nil
+}
+
+func (m *PasswordResetModel) Consume(plaintext string) (int, error) {
+	if plaintext == ValidResetToken {
+		return 1, nil
+	}
+
+	return 0, models.ErrInvalidCredentials
+}
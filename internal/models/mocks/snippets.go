@@ -6,28 +6,309 @@ import (
 )
 
 var mockSnippet = &models.Snippet{
-	ID:      1,
-	Title:   "Snippet Title",
-	Content: "Snippet Content",
-	Created: time.Now(),
-	Expires: time.Now(),
+	ID:         1,
+	Slug:       "mockSnippet1",
+	Title:      "Snippet Title",
+	Content:    "Snippet Content",
+	Language:   "plaintext",
+	Visibility: models.VisibilityPublic,
+	Created:    time.Now(),
+	Updated:    time.Now(),
+	Expires:    time.Now().Add(24 * time.Hour),
+	UserID:     1,
+	UserName:   "User",
+}
+
+// snippet owned by another user, forked from mockSnippet
+var mockForeignSnippet = &models.Snippet{
+	ID:         2,
+	Slug:       "mockSnippet2",
+	Title:      "Foreign Title",
+	Content:    "Foreign Content",
+	Language:   "plaintext",
+	Visibility: models.VisibilityPublic,
+	Created:    time.Now(),
+	Updated:    time.Now(),
+	Expires:    time.Now().Add(24 * time.Hour),
+	UserID:     2,
+	UserName:   "Alice",
+	ForkedFrom: 1,
+	Source: &models.ForkSource{
+		Slug:       "mockSnippet1",
+		Visibility: models.VisibilityPublic,
+		UserID:     1,
+		Expires:    time.Now().Add(24 * time.Hour),
+	},
+}
+
+var mockExpiredSnippet = &models.Snippet{
+	ID:         3,
+	Slug:       "mockSnippet3",
+	Title:      "Expired Title",
+	Content:    "Expired Content",
+	Language:   "plaintext",
+	Visibility: models.VisibilityPublic,
+	Created:    time.Now().Add(-48 * time.Hour),
+	Updated:    time.Now().Add(-48 * time.Hour),
+	Expires:    time.Now().Add(-24 * time.Hour),
+	UserID:     1,
+	UserName:   "User",
+}
+
+// snippet with html in user provided fields
+var mockScriptSnippet = &models.Snippet{
+	ID:         4,
+	Slug:       "mockSnippet4",
+	Title:      "<script>alert('title')</script>",
+	Content:    "<script>alert('content')</script>",
+	Language:   "plaintext",
+	Visibility: models.VisibilityPublic,
+	Created:    time.Now(),
+	Updated:    time.Now(),
+	Expires:    time.Now().Add(24 * time.Hour),
+	UserID:     2,
+	UserName:   "<script>alert('name')</script>",
+}
+
+// private snippet of another user, forked from mockSnippet
+var mockPrivateSnippet = &models.Snippet{
+	ID:         5,
+	Slug:       "mockSnippet5",
+	Title:      "Private Title",
+	Content:    "Private Content",
+	Language:   "plaintext",
+	Visibility: models.VisibilityPrivate,
+	Created:    time.Now(),
+	Updated:    time.Now(),
+	Expires:    time.Now().Add(24 * time.Hour),
+	UserID:     2,
+	UserName:   "Alice",
+	ForkedFrom: 1,
+}
+
+var mockUnlistedSnippet = &models.Snippet{
+	ID:         6,
+	Slug:       "mockSnippet6",
+	Title:      "Unlisted Title",
+	Content:    "Unlisted Content",
+	Language:   "plaintext",
+	Visibility: models.VisibilityUnlisted,
+	Created:    time.Now(),
+	Updated:    time.Now(),
+	Expires:    time.Now().Add(24 * time.Hour),
+	UserID:     2,
+	UserName:   "Alice",
+}
+
+// public snippet of user 1 forked from unlisted snippet of another user
+var mockUnlistedFork = &models.Snippet{
+	ID:         7,
+	Slug:       "mockSnippet7",
+	Title:      "Unlisted Fork Title",
+	Content:    "Unlisted Fork Content",
+	Language:   "plaintext",
+	Visibility: models.VisibilityPublic,
+	Created:    time.Now(),
+	Updated:    time.Now(),
+	Expires:    time.Now().Add(24 * time.Hour),
+	UserID:     1,
+	UserName:   "User",
+	ForkedFrom: 6,
+	Source: &models.ForkSource{
+		Slug:       "mockSnippet6",
+		Visibility: models.VisibilityUnlisted,
+		UserID:     2,
+		Expires:    time.Now().Add(24 * time.Hour),
+	},
+}
+
+// revisions of mockSnippet, newest first
+var mockRevisions = []*models.Revision{
+	{
+		ID:         2,
+		SnippetID:  1,
+		Number:     2,
+		Title:      "Snippet Title",
+		Content:    "Snippet Content",
+		Language:   "plaintext",
+		EditorID:   1,
+		EditorName: "User",
+		Created:    time.Now(),
+	},
+	{
+		ID:         1,
+		SnippetID:  1,
+		Number:     1,
+		Title:      "Snippet Title",
+		Content:    "Old Content",
+		Language:   "plaintext",
+		EditorID:   1,
+		EditorName: "User",
+		Created:    time.Now().Add(-time.Hour),
+	},
+}
+
+var mockSnippets = []*models.Snippet{
+	mockSnippet,
+	mockForeignSnippet,
+	mockExpiredSnippet,
+	mockScriptSnippet,
+	mockPrivateSnippet,
+	mockUnlistedSnippet,
+	mockUnlistedFork,
 }
 
 type SnippetModel struct{}
 
-func (m *SnippetModel) Create(title, content string, expires int) (int, error) {
-	return 2, nil
+func (m *SnippetModel) Create(title, content, language, visibility string, expires, userID, forkedFrom int) (string, error) {
+	return mockForeignSnippet.Slug, nil
+}
+
+func (m *SnippetModel) Get(id, viewerID int) (*models.Snippet, error) {
+	for _, snip := range mockSnippets {
+		if snip.ID == id {
+			return visible(snip, viewerID)
+		}
+	}
+
+	return nil, models.ErrNoRecord
 }
 
-func (m *SnippetModel) Get(id int) (*models.Snippet, error) {
-	switch id {
+func (m *SnippetModel) GetBySlug(slug string, viewerID int) (*models.Snippet, error) {
+	snip, err := m.GetBySlugIncludingExpired(slug)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return visible(snip, viewerID)
+}
+
+func (m *SnippetModel) GetBySlugIncludingExpired(slug string) (*models.Snippet, error) {
+	for _, snip := range mockSnippets {
+		if snip.Slug == slug {
+			return snip, nil
+		}
+	}
+
+	return nil, models.ErrNoRecord
+}
+
+func (m *SnippetModel) Latest() ([]*models.Snippet, error) {
+	return []*models.Snippet{mockSnippet}, nil
+}
+
+func (m *SnippetModel) Update(id int, title, content, language, visibility string, expires, editorID int) error {
+	return exists(id)
+}
+
+func (m *SnippetModel) Delete(id int) error {
+	return exists(id)
+}
+
+func (m *SnippetModel) ByUser(userID int, sort string, limit, offset int) ([]*models.Snippet, int, error) {
+	switch userID {
 	case 1:
-		return mockSnippet, nil
+		return paginate([]*models.Snippet{mockSnippet, mockExpiredSnippet}, limit, offset), 2, nil
 	default:
+		return []*models.Snippet{}, 0, nil
+	}
+}
+
+func (m *SnippetModel) Search(query string, limit, offset int) ([]*models.Snippet, int, error) {
+	active := []*models.Snippet{}
+
+	for _, snip := range mockSnippets {
+		if !snip.Expired() && snip.Visibility == models.VisibilityPublic {
+			active = append(active, snip)
+		}
+	}
+
+	matches := models.RankSnippets(query, active)
+
+	return paginate(matches, limit, offset), len(matches), nil
+}
+
+func (m *SnippetModel) Forks(snippetID int) (int, error) {
+	forks := 0
+
+	for _, snip := range mockSnippets {
+		if snip.ForkedFrom == snippetID && snip.Visibility == models.VisibilityPublic && !snip.Expired() {
+			forks++
+		}
+	}
+
+	return forks, nil
+}
+
+func (m *SnippetModel) DeleteExpired(before time.Time, limit int) (int, error) {
+	deleted := 0
+
+	for _, snip := range mockSnippets {
+		if !snip.Expires.After(before) && deleted < limit {
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+func paginate(snippets []*models.Snippet, limit, offset int) []*models.Snippet {
+	if offset >= len(snippets) {
+		return []*models.Snippet{}
+	}
+
+	end := offset + limit
+
+	if end > len(snippets) {
+		end = len(snippets)
+	}
+
+	return snippets[offset:end]
+}
+
+// visible filters snippet same way as database queries do
+func visible(snip *models.Snippet, viewerID int) (*models.Snippet, error) {
+	if snip.Expired() || !snip.VisibleTo(viewerID) {
 		return nil, models.ErrNoRecord
 	}
+
+	return snip, nil
 }
 
-func (m *SnippetModel) Latest() ([]*models.Snippet, error) {
-	return []*models.Snippet{mockSnippet}, nil
+func exists(id int) error {
+	for _, snip := range mockSnippets {
+		if snip.ID == id {
+			return nil
+		}
+	}
+
+	return models.ErrNoRecord
+}
+
+func (m *SnippetModel) Revisions(snippetID int) ([]*models.Revision, error) {
+	revisions := []*models.Revision{}
+
+	for _, rev := range mockRevisions {
+		if rev.SnippetID == snippetID {
+			revisions = append(revisions, rev)
+		}
+	}
+
+	return revisions, nil
+}
+
+func (m *SnippetModel) Revision(snippetID, number int) (*models.Revision, error) {
+	for _, rev := range mockRevisions {
+		if rev.SnippetID == snippetID && rev.Number == number {
+			return rev, nil
+		}
+	}
+
+	return nil, models.ErrNoRecord
+}
+
+func (m *SnippetModel) Restore(snippetID, number, editorID int) error {
+	_, err := m.Revision(snippetID, number)
+	return err
 }
@@ -0,0 +1,62 @@
+package mocks
+
+import (
+	"snippetbox/internal/models"
+	"sync"
+	"time"
+)
+
+// token accepted by mock for user 1
+const ValidToken = "sbx_valid"
+
+type TokenModel struct {
+	mu sync.Mutex
+	// users whose tokens were revoked by DeleteAllForUser
+	revoked map[int]bool
+}
+
+func (m *TokenModel) Create(userID int, name string) (string, error) {
+	return ValidToken, nil
+}
+
+func (m *TokenModel) ByUser(userID int) ([]*models.Token, error) {
+	if userID == 1 {
+		return []*models.Token{
+			{ID: 1, UserID: 1, Name: "CI", Created: time.Now()},
+		}, nil
+	}
+
+	return []*models.Token{}, nil
+}
+
+func (m *TokenModel) Delete(id, userID int) error {
+	if id == 1 && userID == 1 {
+		return nil
+	}
+
+	return models.ErrNoRecord
+}
+
+func (m *TokenModel) DeleteAllForUser(userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.revoked == nil {
+		m.revoked = map[int]bool{}
+	}
+
+	m.revoked[userID] = true
+
+	return nil
+}
+
+func (m *TokenModel) Authenticate(plaintext string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if plaintext == ValidToken && !m.revoked[1] {
+		return 1, nil
+	}
+
+	return 0, models.ErrInvalidCredentials
+}
@@ -0,0 +1,50 @@
+package mocks
+
+import "snippetbox/internal/models"
+
+const (
+	// user 4 has two-factor enabled, user 1 doesnt
+	TwoFactorEmail = "totp@test.com"
+	// secret returned by Setup for user 1
+	TOTPSecret        = "JBSWY3DPEHPK3PXP"
+	ValidTOTPCode     = "123456"
+	ValidRecoveryCode = "abcd-efgh-ijkl-mnop"
+)
+
+type TwoFactorModel struct{}
+
+func (m *TwoFactorModel) Enabled(userID int) (bool, error) {
+	return userID == 4, nil
+}
+
+func (m *TwoFactorModel) Setup(userID int) (string, error) {
+	if userID == 4 {
+		return "", models.ErrTwoFactorEnabled
+	}
+
+	return TOTPSecret, nil
+}
+
+func (m *TwoFactorModel) Confirm(userID int, code string) ([]string, error) {
+	if userID == 4 {
+		return nil, models.ErrTwoFactorEnabled
+	}
+
+	if code != ValidTOTPCode {
+		return nil, models.ErrInvalidCredentials
+	}
+
+	return []string{ValidRecoveryCode}, nil
+}
+
+func (m *TwoFactorModel) Verify(userID int, code string) error {
+	if userID == 4 && (code == ValidTOTPCode || code == ValidRecoveryCode) {
+		return nil
+	}
+
+	return models.ErrInvalidCredentials
+}
+
+func (m *TwoFactorModel) Disable(userID int) error {
+	return nil
+}
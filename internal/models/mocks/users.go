@@ -5,15 +5,31 @@ import (
 	"time"
 )
 
+// email of mock user 3, who hasnt verified it yet
+const UnverifiedEmail = "unverified@test.com"
+
 type UserModel struct{}
 
 func (m *UserModel) Get(id int) (*models.User, error) {
 	if id == 1 {
 		u := &models.User{
-			Id:      1,
-			Name:    "User",
-			Email:   "user@test.com",
-			Created: time.Now(),
+			Id:            1,
+			Name:          "User",
+			Email:         "user@test.com",
+			Created:       time.Now(),
+			EmailVerified: true,
+		}
+
+		return u, nil
+	}
+
+	if id == 4 {
+		u := &models.User{
+			Id:            4,
+			Name:          "TOTP User",
+			Email:         TwoFactorEmail,
+			Created:       time.Now(),
+			EmailVerified: true,
 		}
 
 		return u, nil
@@ -22,6 +38,27 @@ func (m *UserModel) Get(id int) (*models.User, error) {
 	return nil, models.ErrNoRecord
 }
 
+func (m *UserModel) GetByEmail(email string) (*models.User, error) {
+	switch email {
+	case "user@test.com":
+		return m.Get(1)
+	case TwoFactorEmail:
+		return m.Get(4)
+	case UnverifiedEmail:
+		return &models.User{Id: 3, Name: "Unverified", Email: UnverifiedEmail, Created: time.Now()}, nil
+	default:
+		return nil, models.ErrNoRecord
+	}
+}
+
+func (m *UserModel) VerifyEmail(id int, email string) error {
+	if (id == 1 && email == "user@test.com") || (id == 3 && email == UnverifiedEmail) {
+		return nil
+	}
+
+	return models.ErrNoRecord
+}
+
 func (m *UserModel) PasswordUpdate(id int, currentPassword, newPassword string) error {
 	if id == 1 {
 		if currentPassword != "password" {
@@ -33,12 +70,12 @@ func (m *UserModel) PasswordUpdate(id int, currentPassword, newPassword string)
 	return models.ErrNoRecord
 }
 
-func (m *UserModel) Create(name, email, password string) error {
+func (m *UserModel) Create(name, email, password string) (int, error) {
 	switch email {
 	case "dupe@example.com":
-		return models.ErrDuplicateEmail
+		return 0, models.ErrDuplicateEmail
 	default:
-		return nil
+		return 2, nil
 	}
 }
 
@@ -47,14 +84,30 @@ func (m *UserModel) Authenticate(email, password string) (int, error) {
 		return 1, nil
 	}
 
+	if email == TwoFactorEmail && password == "password" {
+		return 4, nil
+	}
+
+	if email == UnverifiedEmail && password == "password" {
+		return 0, models.ErrEmailNotVerified
+	}
+
 	return 0, models.ErrInvalidCredentials
 }
 
 func (m *UserModel) Exists(id int) (bool, error) {
 	switch id {
-	case 1:
+	case 1, 4:
 		return true, nil
 	default:
 		return false, nil
 	}
 }
+
+func (m *UserModel) PasswordSet(id int, password string) error {
+	if id == 1 || id == 3 {
+		return nil
+	}
+
+	return models.ErrNoRecord
+}
@@ -0,0 +1,101 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type PasswordResetRepo interface {
+	Create(userID int, ttl time.Duration) (string, error)
+	Valid(plaintext string) (bool, error)
+	Consume(plaintext string) (int, error)
+}
+
+type PasswordResetModel struct {
+	DB *sql.DB
+}
+
+// Create stores reset token valid for ttl and returns its plaintext,
+// only hash is kept like with access tokens
+func (p *PasswordResetModel) Create(userID int, ttl time.Duration) (string, error) {
+	plaintext, err := randomString()
+
+	if err != nil {
+		return "", err
+	}
+
+	// expired tokens are useless, so they are cleaned up here
+	_, err = p.DB.Exec(`DELETE FROM password_resets WHERE expires <= UTC_TIMESTAMP()`)
+
+	if err != nil {
+		return "", err
+	}
+
+	query := `
+	INSERT INTO password_resets (hash, user_id, created, expires)
+	VALUES(?, ?, UTC_TIMESTAMP(), DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? SECOND))
+	`
+	_, err = p.DB.Exec(query, hashToken(plaintext), userID, int(ttl.Seconds()))
+
+	if err != nil {
+		return "", err
+	}
+
+	return plaintext, nil
+}
+
+// Valid reports whether token exists and hasnt expired, without using it
+func (p *PasswordResetModel) Valid(plaintext string) (bool, error) {
+	var valid bool
+
+	query := `SELECT EXISTS(SELECT true FROM password_resets WHERE hash = ? AND expires > UTC_TIMESTAMP())`
+
+	err := p.DB.QueryRow(query, hashToken(plaintext)).Scan(&valid)
+
+	return valid, err
+}
+
+// Consume returns id of user token belongs to and deletes all reset tokens
+// of that user, so neither this nor older links can be used again
+func (p *PasswordResetModel) Consume(plaintext string) (int, error) {
+	tx, err := p.DB.Begin()
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback()
+
+	var userID int
+
+	// row is locked, so concurrent requests cant both use same token
+	query := `
+	SELECT user_id FROM password_resets
+	WHERE hash = ? AND expires > UTC_TIMESTAMP()
+	FOR UPDATE
+	`
+	err = tx.QueryRow(query, hashToken(plaintext)).Scan(&userID)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrInvalidCredentials
+		} else {
+			return 0, err
+		}
+	}
+
+	_, err = tx.Exec(`DELETE FROM password_resets WHERE user_id = ?`, userID)
+
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"snippetbox/internal/tests"
+	"testing"
+	"time"
+)
+
+func Test_PasswordResetModel(t *testing.T) {
+	if testing.Short() {
+		t.Skip("models: skipping integration test")
+	}
+
+	db := newTestDb(t)
+
+	model := PasswordResetModel{DB: db}
+
+	older, err := model.Create(1, time.Hour)
+	tests.NilError(t, err)
+
+	token, err := model.Create(1, time.Hour)
+	tests.NilError(t, err)
+
+	valid, err := model.Valid(token)
+	tests.NilError(t, err)
+	tests.Equal(t, valid, true)
+
+	userID, err := model.Consume(token)
+	tests.NilError(t, err)
+	tests.Equal(t, userID, 1)
+
+	// single use, older tokens of user are gone too
+	_, err = model.Consume(token)
+	tests.Equal(t, err, ErrInvalidCredentials)
+
+	_, err = model.Consume(older)
+	tests.Equal(t, err, ErrInvalidCredentials)
+
+	expired, err := model.Create(1, -time.Second)
+	tests.NilError(t, err)
+
+	valid, err = model.Valid(expired)
+	tests.NilError(t, err)
+	tests.Equal(t, valid, false)
+}
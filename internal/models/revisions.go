@@ -0,0 +1,139 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Revision is immutable copy of snippet saved on every change
+type Revision struct {
+	ID         int
+	SnippetID  int
+	Number     int
+	Title      string
+	Content    string
+	Language   string
+	EditorID   int
+	EditorName string
+	Created    time.Time
+}
+
+// Revisions returns all snippet revisions, newest first
+func (s *SnippetModel) Revisions(snippetID int) ([]*Revision, error) {
+	query := `
+	SELECT r.id, r.snippet_id, r.revision, r.title, r.content, r.language, r.user_id, u.name, r.created
+	FROM snippet_revisions r
+	INNER JOIN users u ON u.id = r.user_id
+	WHERE r.snippet_id = ?
+	ORDER BY r.revision DESC
+	`
+	rows, err := s.DB.Query(query, snippetID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	revisions := []*Revision{}
+
+	for rows.Next() {
+		rev := &Revision{}
+
+		err := rows.Scan(&rev.ID, &rev.SnippetID, &rev.Number, &rev.Title, &rev.Content, &rev.Language, &rev.EditorID, &rev.EditorName, &rev.Created)
+
+		if err != nil {
+			return nil, err
+		}
+
+		revisions = append(revisions, rev)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+func (s *SnippetModel) Revision(snippetID, number int) (*Revision, error) {
+	rev := &Revision{}
+
+	query := `
+	SELECT r.id, r.snippet_id, r.revision, r.title, r.content, r.language, r.user_id, u.name, r.created
+	FROM snippet_revisions r
+	INNER JOIN users u ON u.id = r.user_id
+	WHERE r.snippet_id = ? AND r.revision = ?
+	`
+	err := s.DB.
+		QueryRow(query, snippetID, number).
+		Scan(&rev.ID, &rev.SnippetID, &rev.Number, &rev.Title, &rev.Content, &rev.Language, &rev.EditorID, &rev.EditorName, &rev.Created)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		} else {
+			return nil, err
+		}
+	}
+
+	return rev, nil
+}
+
+// Restore makes old revision current snippet content,
+// it is saved as new revision so history stays linear
+func (s *SnippetModel) Restore(snippetID, number, editorID int) error {
+	tx, err := s.DB.Begin()
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	var title, content, language string
+
+	query := `
+	SELECT title, content, language FROM snippet_revisions
+	WHERE snippet_id = ? AND revision = ?
+	`
+	err = tx.QueryRow(query, snippetID, number).Scan(&title, &content, &language)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoRecord
+		} else {
+			return err
+		}
+	}
+
+	query = `UPDATE snippets SET title = ?, content = ?, language = ?, updated = UTC_TIMESTAMP() WHERE id = ?`
+
+	_, err = tx.Exec(query, title, content, language, snippetID)
+
+	if err != nil {
+		return err
+	}
+
+	err = insertRevision(tx, snippetID, title, content, language, editorID)
+
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// insertRevision appends revision with next number,
+// unique key on (snippet_id, revision) rejects concurrent duplicates
+func insertRevision(tx *sql.Tx, snippetID int, title, content, language string, editorID int) error {
+	query := `
+	INSERT INTO snippet_revisions (snippet_id, revision, title, content, language, user_id, created)
+	SELECT ?, COALESCE(MAX(revision), 0) + 1, ?, ?, ?, ?, UTC_TIMESTAMP()
+	FROM snippet_revisions WHERE snippet_id = ?
+	`
+	_, err := tx.Exec(query, snippetID, title, content, language, editorID, snippetID)
+
+	return err
+}
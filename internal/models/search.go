@@ -0,0 +1,91 @@
+package models
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// title matches are worth more than content matches
+const titleWeight = 2
+
+// SearchTerms splits query into unique lowercase words
+func SearchTerms(query string) []string {
+	words := splitWords(query)
+
+	seen := map[string]bool{}
+	terms := []string{}
+
+	for _, w := range words {
+		if !seen[w] {
+			seen[w] = true
+			terms = append(terms, w)
+		}
+	}
+
+	return terms
+}
+
+// MatchScore is pure Go approximation of MySQL natural language
+// full-text search, 0 means snippet doesnt match query
+func MatchScore(terms []string, snip *Snippet) int {
+	title := wordCounts(snip.Title)
+	content := wordCounts(snip.Content)
+
+	score := 0
+
+	for _, term := range terms {
+		score += title[term]*titleWeight + content[term]
+	}
+
+	return score
+}
+
+// RankSnippets returns snippets matching query ordered by relevance
+func RankSnippets(query string, snippets []*Snippet) []*Snippet {
+	terms := SearchTerms(query)
+
+	type ranked struct {
+		snip  *Snippet
+		score int
+	}
+
+	matches := []ranked{}
+
+	for _, snip := range snippets {
+		if score := MatchScore(terms, snip); score > 0 {
+			matches = append(matches, ranked{snip, score})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return matches[i].snip.ID > matches[j].snip.ID
+	})
+
+	result := make([]*Snippet, len(matches))
+
+	for i := range matches {
+		result[i] = matches[i].snip
+	}
+
+	return result
+}
+
+func wordCounts(text string) map[string]int {
+	counts := map[string]int{}
+
+	for _, w := range splitWords(text) {
+		counts[w]++
+	}
+
+	return counts
+}
+
+func splitWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
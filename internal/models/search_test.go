@@ -0,0 +1,70 @@
+package models
+
+import (
+	"snippetbox/internal/tests"
+	"testing"
+)
+
+func Test_SearchTerms(t *testing.T) {
+	terms := SearchTerms("Go, go  SQL-query!")
+
+	tests.Equal(t, len(terms), 3)
+	tests.Equal(t, terms[0], "go")
+	tests.Equal(t, terms[1], "sql")
+	tests.Equal(t, terms[2], "query")
+}
+
+func Test_RankSnippets(t *testing.T) {
+	snippets := []*Snippet{
+		{ID: 1, Title: "Shell", Content: "echo hello"},
+		{ID: 2, Title: "Go", Content: "fmt.Println(\"hello\")"},
+		{ID: 3, Title: "Go hello world", Content: "package main"},
+		{ID: 4, Title: "SQL", Content: "SELECT 1"},
+	}
+
+	testCases := []struct {
+		name  string
+		query string
+		want  []int
+	}{
+		{
+			name:  "Title ranked above content",
+			query: "hello",
+			want:  []int{3, 2, 1},
+		},
+		{
+			name:  "Case insensitive",
+			query: "SELECT",
+			want:  []int{4},
+		},
+		{
+			name:  "Multiple terms",
+			query: "go hello",
+			want:  []int{3, 2, 1},
+		},
+		{
+			name:  "No match",
+			query: "python",
+			want:  []int{},
+		},
+		{
+			name:  "Empty query",
+			query: "",
+			want:  []int{},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			result := RankSnippets(tt.query, snippets)
+
+			tests.Equal(t, len(result), len(tt.want))
+
+			for i := range result {
+				if i < len(tt.want) {
+					tests.Equal(t, result[i].ID, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"crypto/rand"
+	"strings"
+)
+
+// SlugLength gives 62^12 (~2^71) possible slugs
+const SlugLength = 12
+
+const slugAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// largest multiple of alphabet length that fits in byte,
+// bytes above it are skipped so every char is equally likely
+const slugMaxByte = 256 - 256%len(slugAlphabet)
+
+// ValidSlug reports whether s looks like generated slug
+func ValidSlug(s string) bool {
+	if len(s) != SlugLength {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte(slugAlphabet, s[i]) < 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// generateSlug returns random URL-safe base62 string.
+// Slug always has a letter so it cant be mistaken for numeric id.
+func generateSlug() (string, error) {
+	slug := make([]byte, 0, SlugLength)
+	buf := make([]byte, SlugLength)
+
+	for {
+		slug = slug[:0]
+		hasLetter := false
+
+		for len(slug) < SlugLength {
+			_, err := rand.Read(buf)
+
+			if err != nil {
+				return "", err
+			}
+
+			for _, b := range buf {
+				if int(b) >= slugMaxByte || len(slug) == SlugLength {
+					continue
+				}
+
+				c := slugAlphabet[int(b)%len(slugAlphabet)]
+
+				if c > '9' {
+					hasLetter = true
+				}
+
+				slug = append(slug, c)
+			}
+		}
+
+		if hasLetter {
+			return string(slug), nil
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"snippetbox/internal/tests"
+	"testing"
+)
+
+func Test_generateSlug(t *testing.T) {
+	seen := map[string]bool{}
+
+	for i := 0; i < 1000; i++ {
+		slug, err := generateSlug()
+
+		tests.NilError(t, err)
+		tests.Equal(t, ValidSlug(slug), true)
+
+		if seen[slug] {
+			t.Fatalf("duplicate slug %s", slug)
+		}
+
+		seen[slug] = true
+	}
+}
+
+func Test_ValidSlug(t *testing.T) {
+	testCases := []struct {
+		name string
+		slug string
+		want bool
+	}{
+		{
+			name: "Valid",
+			slug: "aZ09bY18cX27",
+			want: true,
+		},
+		{
+			name: "Too short",
+			slug: "aZ09",
+			want: false,
+		},
+		{
+			name: "Too long",
+			slug: "aZ09bY18cX27w",
+			want: false,
+		},
+		{
+			name: "Not base62",
+			slug: "aZ09bY18cX-_",
+			want: false,
+		},
+		{
+			name: "Empty",
+			slug: "",
+			want: false,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			tests.Equal(t, ValidSlug(tt.slug), tt.want)
+		})
+	}
+}
@@ -2,59 +2,215 @@ package models
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"strings"
 	"time"
+
+	"github.com/go-sql-driver/mysql"
 )
 
+// Snippet is exposed by its slug, sequential id isnt shown to users
 type Snippet struct {
-	ID      int
-	Title   string
-	Content string
-	Created time.Time
-	Expires time.Time
+	ID         int       `json:"-"`
+	Slug       string    `json:"slug"`
+	Title      string    `json:"title"`
+	Content    string    `json:"content"`
+	Language   string    `json:"language"`
+	Visibility string    `json:"visibility"`
+	Created    time.Time `json:"created"`
+	Updated    time.Time `json:"updated"`
+	Expires    time.Time `json:"expires"`
+	UserID     int       `json:"user_id"`
+	UserName   string    `json:"user_name,omitempty"`
+	// id of snippet this one was forked from, 0 if it wasnt
+	ForkedFrom int `json:"-"`
+	// nil if snippet wasnt forked or source is deleted
+	Source *ForkSource `json:"forked_from,omitempty"`
+}
+
+// ForkSource is snippet fork was made from
+type ForkSource struct {
+	Slug       string
+	Visibility string
+	UserID     int
+	Expires    time.Time
+}
+
+// MarshalJSON exposes source by its slug
+func (f *ForkSource) MarshalJSON() ([]byte, error) {
+	return json.Marshal(f.Slug)
+}
+
+// SourceVisibleTo reports whether user with given id (0 for anonymous) can
+// follow link to source of fork. Unlisted sources are shown only to their
+// creator, otherwise public fork would reveal their link.
+func (s *Snippet) SourceVisibleTo(userID int) bool {
+	src := s.Source
+
+	if src == nil || !src.Expires.After(time.Now()) {
+		return false
+	}
+
+	return src.Visibility == VisibilityPublic || src.UserID == userID
+}
+
+// Expired reports whether snippet is past its expiry date
+func (s *Snippet) Expired() bool {
+	return !s.Expires.After(time.Now())
 }
 
+// snippet visibility levels
+const (
+	// listed on home page and in search
+	VisibilityPublic = "public"
+	// reachable only by direct link
+	VisibilityUnlisted = "unlisted"
+	// reachable only by its creator
+	VisibilityPrivate = "private"
+)
+
+var Visibilities = []string{VisibilityPublic, VisibilityUnlisted, VisibilityPrivate}
+
+// VisibleTo reports whether user with given id (0 for anonymous) can see snippet
+func (s *Snippet) VisibleTo(userID int) bool {
+	return s.Visibility != VisibilityPrivate || s.UserID == userID
+}
+
+// KeepExpires passed to Update leaves expiry of snippet unchanged
+const KeepExpires = 0
+
+// columns snippets can be ordered by
+const (
+	SortCreated = "created"
+	SortExpires = "expires"
+)
+
 type SnippetRepo interface {
-	Create(title, content string, expires int) (int, error)
-	Get(id int) (*Snippet, error)
+	Create(title, content, language, visibility string, expires, userID, forkedFrom int) (string, error)
+	Get(id, viewerID int) (*Snippet, error)
+	GetBySlug(slug string, viewerID int) (*Snippet, error)
+	GetBySlugIncludingExpired(slug string) (*Snippet, error)
 	Latest() ([]*Snippet, error)
+	Update(id int, title, content, language, visibility string, expires, editorID int) error
+	Delete(id int) error
+	Revisions(snippetID int) ([]*Revision, error)
+	Revision(snippetID, number int) (*Revision, error)
+	Restore(snippetID, number, editorID int) error
+	ByUser(userID int, sort string, limit, offset int) ([]*Snippet, int, error)
+	Search(query string, limit, offset int) ([]*Snippet, int, error)
+	Forks(snippetID int) (int, error)
+	DeleteExpired(before time.Time, limit int) (int, error)
 }
 
 type SnippetModel struct {
 	DB *sql.DB
 }
 
-func (s *SnippetModel) Create(title, content string, expires int) (int, error) {
+// attempts to insert snippet before giving up on slug collisions
+const slugAttempts = 3
+
+// Create inserts snippet with its first revision and returns its slug,
+// forkedFrom is id of source snippet or 0
+func (s *SnippetModel) Create(title, content, language, visibility string, expires, userID, forkedFrom int) (string, error) {
+	for attempt := 1; ; attempt++ {
+		slug, err := generateSlug()
+
+		if err != nil {
+			return "", err
+		}
+
+		err = s.insert(slug, title, content, language, visibility, expires, userID, forkedFrom)
+
+		if err == nil {
+			return slug, nil
+		}
+
+		if !isDuplicateSlug(err) || attempt == slugAttempts {
+			return "", err
+		}
+	}
+}
+
+func (s *SnippetModel) insert(slug, title, content, language, visibility string, expires, userID, forkedFrom int) error {
+	tx, err := s.DB.Begin()
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
 	// ? used as placeholder to avoid SQL injections
 	query := `
-	INSERT INTO snippets (title, content, created, expires)
-	VALUES(?, ?, UTC_TIMESTAMP(), DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? DAY))
+	INSERT INTO snippets (slug, title, content, language, visibility, created, updated, expires, user_id, forked_from)
+	VALUES(?, ?, ?, ?, ?, UTC_TIMESTAMP(), UTC_TIMESTAMP(), DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? DAY), ?, NULLIF(?, 0))
 	`
-	res, err := s.DB.Exec(query, title, content, expires)
+	res, err := tx.Exec(query, slug, title, content, language, visibility, expires, userID, forkedFrom)
 
 	if err != nil {
-		return 0, err
+		return err
 	}
 
 	id, err := res.LastInsertId()
 
 	if err != nil {
-		return 0, err
+		return err
 	}
 
-	return int(id), nil
+	err = insertRevision(tx, int(id), title, content, language, userID)
+
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (s *SnippetModel) Get(id int) (*Snippet, error) {
-	snip := &Snippet{}
+func isDuplicateSlug(err error) bool {
+	var mySQLError *mysql.MySQLError
+
+	if errors.As(err, &mySQLError) {
+		return mySQLError.Number == 1062 && strings.Contains(mySQLError.Message, "snippets_uc_slug")
+	}
+
+	return false
+}
+
+// Get returns not expired snippet, private snippets are
+// returned only to their creator, viewerID is 0 for anonymous
+func (s *SnippetModel) Get(id, viewerID int) (*Snippet, error) {
+	where := `s.expires > UTC_TIMESTAMP() AND s.id = ?
+	AND (s.visibility <> 'private' OR s.user_id = ?)`
+
+	return s.getWhere(where, id, viewerID)
+}
+
+// GetBySlug works like Get but finds snippet by its slug
+func (s *SnippetModel) GetBySlug(slug string, viewerID int) (*Snippet, error) {
+	where := `s.expires > UTC_TIMESTAMP() AND s.slug = ?
+	AND (s.visibility <> 'private' OR s.user_id = ?)`
+
+	return s.getWhere(where, slug, viewerID)
+}
+
+// GetBySlugIncludingExpired works like GetBySlug but also returns expired
+// snippets, so owners can renew them. Visibility isnt checked, caller must do it.
+func (s *SnippetModel) GetBySlugIncludingExpired(slug string) (*Snippet, error) {
+	return s.getWhere("s.slug = ?", slug)
+}
+
+func (s *SnippetModel) getWhere(where string, args ...any) (*Snippet, error) {
+	var userName string
 
 	query := `
-	SELECT id, title, content, created, expires FROM snippets
-	WHERE expires > UTC_TIMESTAMP() AND id = ?
-	`
-	err := s.DB.
-		QueryRow(query, id).
-		Scan(&snip.ID, &snip.Title, &snip.Content, &snip.Created, &snip.Expires)
+	SELECT ` + snippetColumns + `, u.name
+	FROM snippets s
+	INNER JOIN users u ON u.id = s.user_id
+	LEFT JOIN snippets f ON f.id = s.forked_from
+	WHERE ` + where
+
+	snip, err := scanSnippet(s.DB.QueryRow(query, args...), &userName)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -64,16 +220,18 @@ func (s *SnippetModel) Get(id int) (*Snippet, error) {
 		}
 	}
 
+	snip.UserName = userName
+
 	return snip, nil
 }
 
 func (s *SnippetModel) Latest() ([]*Snippet, error) {
-	snippets := []*Snippet{}
-
 	query := `
-	SELECT id, title, content, created, expires FROM snippets
-    WHERE expires > UTC_TIMESTAMP() 
-	ORDER BY id DESC LIMIT 10
+	SELECT ` + snippetColumns + `
+	FROM snippets s
+	LEFT JOIN snippets f ON f.id = s.forked_from
+	WHERE s.expires > UTC_TIMESTAMP() AND s.visibility = 'public'
+	ORDER BY s.id DESC LIMIT 10
 	`
 
 	rows, err := s.DB.Query(query)
@@ -82,12 +240,247 @@ func (s *SnippetModel) Latest() ([]*Snippet, error) {
 		return nil, err
 	}
 
+	return scanSnippets(rows)
+}
+
+// ByUser returns page of user snippets including expired ones
+// and total number of user snippets
+func (s *SnippetModel) ByUser(userID int, sort string, limit, offset int) ([]*Snippet, int, error) {
+	var total int
+
+	err := s.DB.
+		QueryRow(`SELECT COUNT(*) FROM snippets WHERE user_id = ?`, userID).
+		Scan(&total)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// column name cant be passed as placeholder
+	orderBy := "created"
+
+	if sort == SortExpires {
+		orderBy = "expires"
+	}
+
+	query := `
+	SELECT ` + snippetColumns + `
+	FROM snippets s
+	LEFT JOIN snippets f ON f.id = s.forked_from
+	WHERE s.user_id = ?
+	ORDER BY s.` + orderBy + ` DESC, s.id DESC LIMIT ? OFFSET ?
+	`
+
+	rows, err := s.DB.Query(query, userID, limit, offset)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	snippets, err := scanSnippets(rows)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return snippets, total, nil
+}
+
+// Update changes snippet and stores new content as next revision.
+// expires is number of days from now or KeepExpires.
+// Returns ErrNoRecord if snippet doesnt exist.
+func (s *SnippetModel) Update(id int, title, content, language, visibility string, expires, editorID int) error {
+	tx, err := s.DB.Begin()
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	// row is locked, so snippet cant be deleted before revision is inserted
+	err = tx.QueryRow(`SELECT id FROM snippets WHERE id = ? FOR UPDATE`, id).Scan(&id)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoRecord
+		} else {
+			return err
+		}
+	}
+
+	query := `
+	UPDATE snippets
+	SET title = ?, content = ?, language = ?, visibility = ?, updated = UTC_TIMESTAMP(),
+	expires = IF(? = 0, expires, DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? DAY))
+	WHERE id = ?
+	`
+	_, err = tx.Exec(query, title, content, language, visibility, expires, expires, id)
+
+	if err != nil {
+		return err
+	}
+
+	err = insertRevision(tx, id, title, content, language, editorID)
+
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *SnippetModel) Delete(id int) error {
+	query := `DELETE FROM snippets WHERE id = ?`
+
+	res, err := s.DB.Exec(query, id)
+
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
+
+// DeleteExpired deletes at most limit snippets expired before given time
+// and returns number of deleted snippets
+func (s *SnippetModel) DeleteExpired(before time.Time, limit int) (int, error) {
+	// idx_snippets_expires keeps batches from scanning and sorting whole table
+	query := `DELETE FROM snippets WHERE expires <= ? ORDER BY expires LIMIT ?`
+
+	res, err := s.DB.Exec(query, before.UTC(), limit)
+
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := res.RowsAffected()
+
+	if err != nil {
+		return 0, err
+	}
+
+	return int(rows), nil
+}
+
+// Search returns page of public not expired snippets matching query
+// ordered by relevance and total number of matches
+func (s *SnippetModel) Search(query string, limit, offset int) ([]*Snippet, int, error) {
+	var total int
+
+	countQuery := `
+	SELECT COUNT(*) FROM snippets
+	WHERE expires > UTC_TIMESTAMP() AND visibility = 'public'
+	AND MATCH(title, content) AGAINST(? IN NATURAL LANGUAGE MODE)
+	`
+	err := s.DB.QueryRow(countQuery, query).Scan(&total)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	searchQuery := `
+	SELECT ` + snippetColumns + `
+	FROM snippets s
+	LEFT JOIN snippets f ON f.id = s.forked_from
+	WHERE s.expires > UTC_TIMESTAMP() AND s.visibility = 'public'
+	AND MATCH(s.title, s.content) AGAINST(? IN NATURAL LANGUAGE MODE)
+	ORDER BY MATCH(s.title, s.content) AGAINST(? IN NATURAL LANGUAGE MODE) DESC, s.id DESC
+	LIMIT ? OFFSET ?
+	`
+	rows, err := s.DB.Query(searchQuery, query, query, limit, offset)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	snippets, err := scanSnippets(rows)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return snippets, total, nil
+}
+
+// Forks returns number of public not expired snippets forked from snippet,
+// count is shown to everyone so other forks mustnt be revealed by it
+func (s *SnippetModel) Forks(snippetID int) (int, error) {
+	var forks int
+
+	query := `
+	SELECT COUNT(*) FROM snippets
+	WHERE forked_from = ? AND visibility = 'public' AND expires > UTC_TIMESTAMP()
+	`
+	err := s.DB.QueryRow(query, snippetID).Scan(&forks)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return forks, nil
+}
+
+// columns of snippet s and of source f it was forked from, read by scanSnippet
+const snippetColumns = `s.id, s.slug, s.title, s.content, s.language, s.visibility, s.created, s.updated, s.expires, s.user_id,
+	COALESCE(s.forked_from, 0), f.slug, f.visibility, f.user_id, f.expires`
+
+// rowScanner is *sql.Row or *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanSnippet reads row selected with snippetColumns,
+// values of columns following them are read into extra
+func scanSnippet(row rowScanner, extra ...any) (*Snippet, error) {
+	snip := &Snippet{}
+
+	// source is null when snippet isnt fork or source was deleted
+	var (
+		srcSlug, srcVisibility sql.NullString
+		srcUserID              sql.NullInt64
+		srcExpires             sql.NullTime
+	)
+
+	dest := []any{&snip.ID, &snip.Slug, &snip.Title, &snip.Content, &snip.Language, &snip.Visibility, &snip.Created, &snip.Updated, &snip.Expires, &snip.UserID,
+		&snip.ForkedFrom, &srcSlug, &srcVisibility, &srcUserID, &srcExpires}
+
+	err := row.Scan(append(dest, extra...)...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if srcSlug.Valid {
+		snip.Source = &ForkSource{
+			Slug:       srcSlug.String,
+			Visibility: srcVisibility.String,
+			UserID:     int(srcUserID.Int64),
+			Expires:    srcExpires.Time,
+		}
+	}
+
+	return snip, nil
+}
+
+// scanSnippets reads rows selected with snippetColumns and closes them
+func scanSnippets(rows *sql.Rows) ([]*Snippet, error) {
 	defer rows.Close()
 
-	for rows.Next() {
-		snip := &Snippet{}
+	snippets := []*Snippet{}
 
-		err := rows.Scan(&snip.ID, &snip.Title, &snip.Content, &snip.Created, &snip.Expires)
+	for rows.Next() {
+		snip, err := scanSnippet(rows)
 
 		if err != nil {
 			return nil, err
@@ -102,7 +495,3 @@ func (s *SnippetModel) Latest() ([]*Snippet, error) {
 
 	return snippets, nil
 }
-
-func (s *SnippetModel) Update(title, content string, expires int) (int, error) {
-	return 0, nil
-}
@@ -0,0 +1,7 @@
+INSERT INTO users (name, email, hashed_password, created, email_verified) VALUES (
+    'Test Bob',
+    'user@test.com',
+    '$2a$12$NuTjWXm3KKntReFwyBVHyuf/to.HEwTy.eS206TNfkGfr6HzGJSWG',
+    '2023-04-29 10:00:00',
+    TRUE
+);
@@ -3,6 +3,7 @@ package models
 import (
 	"database/sql"
 	"os"
+	"snippetbox/internal/models/migrations"
 	"testing"
 )
 
@@ -13,26 +14,27 @@ func newTestDb(t *testing.T) *sql.DB {
 		t.Fatal(err)
 	}
 
-	script, err := os.ReadFile("./migrations/setup.sql")
+	// same migrations as production database
+	applied, err := migrations.Up(db)
 
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	_, err = db.Exec(string(script))
+	script, err := os.ReadFile("./testdata/seed.sql")
 
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	t.Cleanup(func() {
-		script, err := os.ReadFile("./migrations/teardown.sql")
+	_, err = db.Exec(string(script))
 
-		if err != nil {
-			t.Fatal(err)
-		}
+	if err != nil {
+		t.Fatal(err)
+	}
 
-		_, err = db.Exec(string(script))
+	t.Cleanup(func() {
+		_, err := migrations.Down(db, len(applied))
 
 		if err != nil {
 			t.Fatal(err)
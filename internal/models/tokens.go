@@ -0,0 +1,170 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"time"
+)
+
+// prefix makes tokens easy to recognize in configs and secret scanners
+const tokenPrefix = "sbx_"
+
+type Token struct {
+	ID      int
+	UserID  int
+	Name    string
+	Created time.Time
+}
+
+type TokenRepo interface {
+	Create(userID int, name string) (string, error)
+	ByUser(userID int) ([]*Token, error)
+	Delete(id, userID int) error
+	DeleteAllForUser(userID int) error
+	Authenticate(plaintext string) (int, error)
+}
+
+type TokenModel struct {
+	DB *sql.DB
+}
+
+// Create stores new token and returns its plaintext,
+// only hash is kept so it cant be shown again
+func (t *TokenModel) Create(userID int, name string) (string, error) {
+	plaintext, err := generateToken()
+
+	if err != nil {
+		return "", err
+	}
+
+	query := `
+	INSERT INTO tokens (user_id, name, hash, created)
+	VALUES(?, ?, ?, UTC_TIMESTAMP())
+	`
+	_, err = t.DB.Exec(query, userID, name, hashToken(plaintext))
+
+	if err != nil {
+		return "", err
+	}
+
+	return plaintext, nil
+}
+
+func (t *TokenModel) ByUser(userID int) ([]*Token, error) {
+	query := `
+	SELECT id, user_id, name, created FROM tokens
+	WHERE user_id = ?
+	ORDER BY id DESC
+	`
+	rows, err := t.DB.Query(query, userID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	tokens := []*Token{}
+
+	for rows.Next() {
+		token := &Token{}
+
+		err := rows.Scan(&token.ID, &token.UserID, &token.Name, &token.Created)
+
+		if err != nil {
+			return nil, err
+		}
+
+		tokens = append(tokens, token)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// Delete revokes token if it belongs to user
+func (t *TokenModel) Delete(id, userID int) error {
+	res, err := t.DB.Exec(`DELETE FROM tokens WHERE id = ? AND user_id = ?`, id, userID)
+
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
+
+// DeleteAllForUser revokes every token of user
+func (t *TokenModel) DeleteAllForUser(userID int) error {
+	_, err := t.DB.Exec(`DELETE FROM tokens WHERE user_id = ?`, userID)
+
+	return err
+}
+
+// Authenticate returns id of token owner, tokens of users
+// with unverified email are rejected
+func (t *TokenModel) Authenticate(plaintext string) (int, error) {
+	var userID int
+
+	err := t.DB.
+		QueryRow(`
+		SELECT t.user_id FROM tokens t
+		JOIN users u ON u.id = t.user_id
+		WHERE t.hash = ? AND u.email_verified
+		`, hashToken(plaintext)).
+		Scan(&userID)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrInvalidCredentials
+		} else {
+			return 0, err
+		}
+	}
+
+	return userID, nil
+}
+
+func generateToken() (string, error) {
+	s, err := randomString()
+
+	if err != nil {
+		return "", err
+	}
+
+	return tokenPrefix + s, nil
+}
+
+// randomString returns 256 random bits encoded for use in URLs
+func randomString() (string, error) {
+	b := make([]byte, 32)
+
+	_, err := rand.Read(b)
+
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// tokens are random so plain sha256 is enough, no need for bcrypt
+func hashToken(plaintext string) []byte {
+	hash := sha256.Sum256([]byte(plaintext))
+	return hash[:]
+}
@@ -0,0 +1,308 @@
+package models
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"snippetbox/internal/encrypt"
+	"snippetbox/internal/totp"
+	"strings"
+	"time"
+)
+
+// number of recovery codes given when two-factor is enabled
+const RecoveryCodeCount = 10
+
+// 80 bits each, sha256 is enough like with tokens
+const recoveryCodeLength = 10
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+type TwoFactorRepo interface {
+	Enabled(userID int) (bool, error)
+	Setup(userID int) (string, error)
+	Confirm(userID int, code string) ([]string, error)
+	Verify(userID int, code string) error
+	Disable(userID int) error
+}
+
+// TwoFactorModel stores TOTP secrets encrypted by Box
+type TwoFactorModel struct {
+	DB  *sql.DB
+	Box *encrypt.Box
+}
+
+func (m *TwoFactorModel) Enabled(userID int) (bool, error) {
+	var enabled bool
+
+	query := "SELECT EXISTS(SELECT true FROM totp_secrets WHERE user_id = ? AND enabled)"
+
+	err := m.DB.QueryRow(query, userID).Scan(&enabled)
+
+	return enabled, err
+}
+
+// Setup returns secret waiting for confirmation, new one is created
+// if user has none. Secret isnt used for login until confirmed.
+func (m *TwoFactorModel) Setup(userID int) (string, error) {
+	var sealed []byte
+	var enabled bool
+
+	err := m.DB.
+		QueryRow(`SELECT secret, enabled FROM totp_secrets WHERE user_id = ?`, userID).
+		Scan(&sealed, &enabled)
+
+	if err == nil {
+		if enabled {
+			return "", ErrTwoFactorEnabled
+		}
+
+		return m.open(userID, sealed)
+	}
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		return "", err
+	}
+
+	secret, err := totp.GenerateSecret()
+
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err = m.Box.Seal([]byte(secret), secretContext(userID))
+
+	if err != nil {
+		return "", err
+	}
+
+	query := `
+	INSERT INTO totp_secrets (user_id, secret, created)
+	VALUES(?, ?, UTC_TIMESTAMP())
+	`
+	_, err = m.DB.Exec(query, userID, sealed)
+
+	if err != nil {
+		return "", err
+	}
+
+	return secret, nil
+}
+
+// Confirm enables two-factor if code matches secret from Setup
+// and returns recovery codes, only their hashes are stored
+func (m *TwoFactorModel) Confirm(userID int, code string) ([]string, error) {
+	tx, err := m.DB.Begin()
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	var sealed []byte
+	var enabled bool
+
+	err = tx.
+		QueryRow(`SELECT secret, enabled FROM totp_secrets WHERE user_id = ? FOR UPDATE`, userID).
+		Scan(&sealed, &enabled)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		} else {
+			return nil, err
+		}
+	}
+
+	if enabled {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	secret, err := m.open(userID, sealed)
+
+	if err != nil {
+		return nil, err
+	}
+
+	step, ok := totp.Validate(secret, code, time.Now())
+
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+
+	_, err = tx.Exec(`UPDATE totp_secrets SET enabled = TRUE, last_step = ? WHERE user_id = ?`, step, userID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`DELETE FROM recovery_codes WHERE user_id = ?`, userID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, RecoveryCodeCount)
+
+	for i := 0; i < RecoveryCodeCount; i++ {
+		code, err := generateRecoveryCode()
+
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = tx.Exec(`INSERT INTO recovery_codes (user_id, hash) VALUES(?, ?)`,
+			userID, hashToken(normalizeRecoveryCode(code)))
+
+		if err != nil {
+			return nil, err
+		}
+
+		codes = append(codes, code)
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// Verify accepts current TOTP code or unused recovery code.
+// Each TOTP code and recovery code works only once.
+func (m *TwoFactorModel) Verify(userID int, code string) error {
+	code = strings.TrimSpace(code)
+
+	if len(code) != totp.Digits {
+		return m.useRecoveryCode(userID, code)
+	}
+
+	tx, err := m.DB.Begin()
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	var sealed []byte
+	var lastStep int64
+
+	err = tx.
+		QueryRow(`SELECT secret, last_step FROM totp_secrets WHERE user_id = ? AND enabled FOR UPDATE`, userID).
+		Scan(&sealed, &lastStep)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidCredentials
+		} else {
+			return err
+		}
+	}
+
+	secret, err := m.open(userID, sealed)
+
+	if err != nil {
+		return err
+	}
+
+	step, ok := totp.Validate(secret, code, time.Now())
+
+	// code seen by someone looking over shoulder cant be replayed
+	if !ok || step <= lastStep {
+		return ErrInvalidCredentials
+	}
+
+	_, err = tx.Exec(`UPDATE totp_secrets SET last_step = ? WHERE user_id = ?`, step, userID)
+
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m *TwoFactorModel) useRecoveryCode(userID int, code string) error {
+	res, err := m.DB.Exec(`DELETE FROM recovery_codes WHERE user_id = ? AND hash = ?`,
+		userID, hashToken(normalizeRecoveryCode(code)))
+
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrInvalidCredentials
+	}
+
+	return nil
+}
+
+func (m *TwoFactorModel) Disable(userID int) error {
+	tx, err := m.DB.Begin()
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`DELETE FROM recovery_codes WHERE user_id = ?`, userID)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`DELETE FROM totp_secrets WHERE user_id = ?`, userID)
+
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m *TwoFactorModel) open(userID int, sealed []byte) (string, error) {
+	secret, err := m.Box.Open(sealed, secretContext(userID))
+
+	if err != nil {
+		return "", err
+	}
+
+	return string(secret), nil
+}
+
+// secretContext binds encrypted secret to user
+func secretContext(userID int) []byte {
+	return []byte(fmt.Sprintf("totp:%d", userID))
+}
+
+// generateRecoveryCode returns code like abcd-efgh-ijkl-mnop
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, recoveryCodeLength)
+
+	_, err := rand.Read(b)
+
+	if err != nil {
+		return "", err
+	}
+
+	code := strings.ToLower(recoveryEncoding.EncodeToString(b))
+
+	return code[0:4] + "-" + code[4:8] + "-" + code[8:12] + "-" + code[12:16], nil
+}
+
+// normalizeRecoveryCode ignores case and separators typed by user
+func normalizeRecoveryCode(code string) string {
+	return strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(code))
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"snippetbox/internal/encrypt"
+	"snippetbox/internal/tests"
+	"snippetbox/internal/totp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_generateRecoveryCode(t *testing.T) {
+	code, err := generateRecoveryCode()
+	tests.NilError(t, err)
+
+	tests.Equal(t, len(code), 19)
+	tests.Equal(t, strings.Count(code, "-"), 3)
+	tests.Equal(t, normalizeRecoveryCode(strings.ToUpper(code)), strings.ReplaceAll(code, "-", ""))
+	tests.Equal(t, normalizeRecoveryCode(" abcd efgh "), "abcdefgh")
+}
+
+func Test_TwoFactorModel(t *testing.T) {
+	if testing.Short() {
+		t.Skip("models: skipping integration test")
+	}
+
+	db := newTestDb(t)
+
+	box, err := encrypt.New(strings.Repeat("ab", encrypt.KeyLength))
+	tests.NilError(t, err)
+
+	model := TwoFactorModel{DB: db, Box: box}
+
+	secret, err := model.Setup(1)
+	tests.NilError(t, err)
+
+	// setup again returns same pending secret
+	again, err := model.Setup(1)
+	tests.NilError(t, err)
+	tests.Equal(t, again, secret)
+
+	var stored []byte
+	err = db.QueryRow(`SELECT secret FROM totp_secrets WHERE user_id = 1`).Scan(&stored)
+	tests.NilError(t, err)
+
+	if strings.Contains(string(stored), secret) {
+		t.Fatal("secret stored in plaintext")
+	}
+
+	_, err = model.Confirm(1, "000000")
+	tests.Equal(t, err, ErrInvalidCredentials)
+
+	// code from previous step, so next one can be verified
+	code, err := totp.Code(secret, totp.Step(time.Now())-1)
+	tests.NilError(t, err)
+
+	codes, err := model.Confirm(1, code)
+	tests.NilError(t, err)
+	tests.Equal(t, len(codes), RecoveryCodeCount)
+
+	enabled, err := model.Enabled(1)
+	tests.NilError(t, err)
+	tests.Equal(t, enabled, true)
+
+	_, err = model.Setup(1)
+	tests.Equal(t, err, ErrTwoFactorEnabled)
+
+	// used code is rejected
+	tests.Equal(t, model.Verify(1, code), ErrInvalidCredentials)
+
+	code, err = totp.Code(secret, totp.Step(time.Now()))
+	tests.NilError(t, err)
+	tests.NilError(t, model.Verify(1, code))
+
+	tests.NilError(t, model.Verify(1, strings.ToUpper(codes[0])))
+	tests.Equal(t, model.Verify(1, codes[0]), ErrInvalidCredentials)
+
+	tests.NilError(t, model.Disable(1))
+
+	enabled, err = model.Enabled(1)
+	tests.NilError(t, err)
+	tests.Equal(t, enabled, false)
+}
@@ -16,28 +16,44 @@ type User struct {
 	Email          string
 	HashedPassword []byte
 	Created        time.Time
+	EmailVerified  bool
 }
 
 type UserRepo interface {
 	Get(id int) (*User, error)
-	Create(name, email, password string) error
+	Create(name, email, password string) (int, error)
+	GetByEmail(email string) (*User, error)
+	VerifyEmail(id int, email string) error
 	Exists(id int) (bool, error)
 	Authenticate(email, password string) (int, error)
 	PasswordUpdate(id int, currentPassword, newPassword string) error
+	PasswordSet(id int, password string) error
 }
 
+// bcrypt cost used when UserModel.BcryptCost isnt set
+const DefaultBcryptCost = 12
+
 type UserModel struct {
-	DB *sql.DB
+	DB         *sql.DB
+	BcryptCost int
+}
+
+func (u *UserModel) bcryptCost() int {
+	if u.BcryptCost == 0 {
+		return DefaultBcryptCost
+	}
+
+	return u.BcryptCost
 }
 
 func (u *UserModel) Get(id int) (*User, error) {
 	user := &User{}
 
-	query := `SELECT email, name, created from users where id = ?`
+	query := `SELECT id, email, name, created, email_verified from users where id = ?`
 
 	err := u.DB.
 		QueryRow(query, id).
-		Scan(&user.Email, &user.Name, &user.Created)
+		Scan(&user.Id, &user.Email, &user.Name, &user.Created, &user.EmailVerified)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -50,11 +66,12 @@ func (u *UserModel) Get(id int) (*User, error) {
 	return user, nil
 }
 
-func (u *UserModel) Create(name, email, password string) error {
-	hashedPass, err := bcrypt.GenerateFromPassword([]byte(password), 12)
+// Create stores user with unverified email and returns its id
+func (u *UserModel) Create(name, email, password string) (int, error) {
+	hashedPass, err := bcrypt.GenerateFromPassword([]byte(password), u.bcryptCost())
 
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	// ? used as placeholder to avoid SQL injections
@@ -62,19 +79,67 @@ func (u *UserModel) Create(name, email, password string) error {
 	INSERT INTO users (name, email, hashed_password, created)
     VALUES(?, ?, ?, UTC_TIMESTAMP())
 	`
-	_, err = u.DB.Exec(query, name, email, string(hashedPass))
+	res, err := u.DB.Exec(query, name, email, string(hashedPass))
 
 	if err != nil {
 		var mySQLError *mysql.MySQLError
 		if errors.As(err, &mySQLError) {
 			if mySQLError.Number == 1062 && strings.Contains(mySQLError.Message, "users_uc_email") {
-				return ErrDuplicateEmail
+				return 0, ErrDuplicateEmail
 			}
 		}
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+func (u *UserModel) GetByEmail(email string) (*User, error) {
+	user := &User{}
+
+	query := `SELECT id, email, name, created, email_verified from users where email = ?`
+
+	err := u.DB.
+		QueryRow(query, email).
+		Scan(&user.Id, &user.Email, &user.Name, &user.Created, &user.EmailVerified)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoRecord
+		} else {
+			return nil, err
+		}
+	}
+
+	return user, nil
+}
+
+// VerifyEmail marks email of user as verified. Email must still match,
+// so link sent to old address doesnt verify changed one.
+func (u *UserModel) VerifyEmail(id int, email string) error {
+	var exists bool
+
+	query := "SELECT EXISTS(SELECT true FROM users WHERE id = ? AND email = ?)"
+
+	err := u.DB.QueryRow(query, id, email).Scan(&exists)
+
+	if err != nil {
 		return err
 	}
 
-	return nil
+	if !exists {
+		return ErrNoRecord
+	}
+
+	_, err = u.DB.Exec(`UPDATE users SET email_verified = TRUE WHERE id = ?`, id)
+
+	return err
 }
 
 func (u *UserModel) Exists(id int) (bool, error) {
@@ -92,13 +157,14 @@ func (u *UserModel) Exists(id int) (bool, error) {
 func (u *UserModel) Authenticate(email, password string) (int, error) {
 	var id int
 	var hashedPassword []byte
+	var emailVerified bool
 
 	query := `
-	SELECT id, hashed_password from users where email = ?
+	SELECT id, hashed_password, email_verified from users where email = ?
 	`
 	err := u.DB.
 		QueryRow(query, email).
-		Scan(&id, &hashedPassword)
+		Scan(&id, &hashedPassword, &emailVerified)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -118,6 +184,11 @@ func (u *UserModel) Authenticate(email, password string) (int, error) {
 		}
 	}
 
+	// checked after password, so it doesnt reveal which emails are registered
+	if !emailVerified {
+		return 0, ErrEmailNotVerified
+	}
+
 	return id, nil
 }
 
@@ -139,7 +210,7 @@ func (u *UserModel) PasswordUpdate(id int, currentPassword, newPassword string)
 		return ErrInvalidCredentials
 	}
 
-	newHashedPass, err := bcrypt.GenerateFromPassword([]byte(newPassword), 12)
+	newHashedPass, err := bcrypt.GenerateFromPassword([]byte(newPassword), u.bcryptCost())
 
 	if err != nil {
 		return err
@@ -155,3 +226,31 @@ func (u *UserModel) PasswordUpdate(id int, currentPassword, newPassword string)
 
 	return err
 }
+
+// PasswordSet replaces password without checking current one,
+// caller must have verified user some other way
+func (u *UserModel) PasswordSet(id int, password string) error {
+	hashedPass, err := bcrypt.GenerateFromPassword([]byte(password), u.bcryptCost())
+
+	if err != nil {
+		return err
+	}
+
+	res, err := u.DB.Exec(`UPDATE users SET hashed_password = ? WHERE id = ?`, string(hashedPass), id)
+
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
@@ -3,6 +3,8 @@ package models
 import (
 	"snippetbox/internal/tests"
 	"testing"
+
+	"golang.org/x/crypto/bcrypt"
 )
 
 func Test_UserModelExists(t *testing.T) {
@@ -36,7 +38,7 @@ func Test_UserModelExists(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			db := newTestDb(t)
 
-			model := UserModel{db}
+			model := UserModel{DB: db}
 
 			exists, err := model.Exists(tt.userID)
 
@@ -45,3 +47,33 @@ func Test_UserModelExists(t *testing.T) {
 		})
 	}
 }
+
+func Test_UserModelVerifyEmail(t *testing.T) {
+	if testing.Short() {
+		t.Skip("models: skipping integration test")
+	}
+
+	db := newTestDb(t)
+
+	model := UserModel{DB: db, BcryptCost: bcrypt.MinCost}
+
+	id, err := model.Create("Alice", "alice@test.com", "password")
+	tests.NilError(t, err)
+
+	_, err = model.Authenticate("alice@test.com", "password")
+	tests.Equal(t, err, ErrEmailNotVerified)
+
+	err = model.VerifyEmail(id, "other@test.com")
+	tests.Equal(t, err, ErrNoRecord)
+
+	err = model.VerifyEmail(id, "alice@test.com")
+	tests.NilError(t, err)
+
+	authID, err := model.Authenticate("alice@test.com", "password")
+	tests.NilError(t, err)
+	tests.Equal(t, authID, id)
+
+	user, err := model.GetByEmail("alice@test.com")
+	tests.NilError(t, err)
+	tests.Equal(t, user.EmailVerified, true)
+}
@@ -0,0 +1,86 @@
+// Package signer creates tamper-proof expiring tokens, so links sent by
+// email can be checked without storing anything in database.
+package signer
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"time"
+)
+
+// shortest key accepted, same as sha256 output
+const MinKeyLength = 32
+
+var (
+	ErrInvalidToken = errors.New("signer: invalid token")
+	ErrExpiredToken = errors.New("signer: expired token")
+)
+
+type Signer struct {
+	key []byte
+}
+
+func New(key string) (*Signer, error) {
+	if len(key) < MinKeyLength {
+		return nil, errors.New("signer: key is too short")
+	}
+
+	return &Signer{key: []byte(key)}, nil
+}
+
+// Sign returns token carrying subject until expires.
+// Purpose is part of signature, so token for one purpose
+// cant be used for another.
+func (s *Signer) Sign(purpose, subject string, expires time.Time) string {
+	payload := binary.BigEndian.AppendUint64(nil, uint64(expires.Unix()))
+	payload = append(payload, subject...)
+
+	enc := base64.RawURLEncoding
+
+	return enc.EncodeToString(payload) + "." + enc.EncodeToString(s.mac(purpose, payload))
+}
+
+// Verify returns subject of token signed for purpose
+func (s *Signer) Verify(purpose, token string, now time.Time) (string, error) {
+	encPayload, encMAC, ok := strings.Cut(token, ".")
+
+	if !ok {
+		return "", ErrInvalidToken
+	}
+
+	enc := base64.RawURLEncoding
+
+	payload, err := enc.DecodeString(encPayload)
+
+	if err != nil || len(payload) < 8 {
+		return "", ErrInvalidToken
+	}
+
+	mac, err := enc.DecodeString(encMAC)
+
+	if err != nil || !hmac.Equal(mac, s.mac(purpose, payload)) {
+		return "", ErrInvalidToken
+	}
+
+	expires := time.Unix(int64(binary.BigEndian.Uint64(payload[:8])), 0)
+
+	if !now.Before(expires) {
+		return "", ErrExpiredToken
+	}
+
+	return string(payload[8:]), nil
+}
+
+func (s *Signer) mac(purpose string, payload []byte) []byte {
+	h := hmac.New(sha256.New, s.key)
+	h.Write([]byte(purpose))
+	// separator, so purpose cant run into payload
+	h.Write([]byte{0})
+	h.Write(payload)
+
+	return h.Sum(nil)
+}
@@ -0,0 +1,104 @@
+package signer
+
+import (
+	"snippetbox/internal/tests"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testKey = "0123456789abcdef0123456789abcdef"
+
+func Test_New(t *testing.T) {
+	_, err := New("short")
+	tests.Equal(t, err != nil, true)
+
+	_, err = New(testKey)
+	tests.NilError(t, err)
+}
+
+func Test_SignVerify(t *testing.T) {
+	s, err := New(testKey)
+	tests.NilError(t, err)
+
+	other, err := New(strings.ToUpper(testKey))
+	tests.NilError(t, err)
+
+	now := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	token := s.Sign("verify", "1:user@test.com", now.Add(time.Hour))
+
+	// changes expiry in payload, still valid base64
+	tampered := "A" + token[1:]
+
+	if strings.HasPrefix(token, "A") {
+		tampered = "B" + token[1:]
+	}
+
+	testCases := []struct {
+		name       string
+		signer     *Signer
+		purpose    string
+		token      string
+		now        time.Time
+		expSubject string
+		expErr     error
+	}{
+		{
+			name:       "Valid",
+			signer:     s,
+			purpose:    "verify",
+			token:      token,
+			now:        now,
+			expSubject: "1:user@test.com",
+		},
+		{
+			name:    "Expired",
+			signer:  s,
+			purpose: "verify",
+			token:   token,
+			now:     now.Add(time.Hour),
+			expErr:  ErrExpiredToken,
+		},
+		{
+			name:    "Other purpose",
+			signer:  s,
+			purpose: "reset",
+			token:   token,
+			now:     now,
+			expErr:  ErrInvalidToken,
+		},
+		{
+			name:    "Other key",
+			signer:  other,
+			purpose: "verify",
+			token:   token,
+			now:     now,
+			expErr:  ErrInvalidToken,
+		},
+		{
+			name:    "Tampered",
+			signer:  s,
+			purpose: "verify",
+			token:   tampered,
+			now:     now,
+			expErr:  ErrInvalidToken,
+		},
+		{
+			name:    "Malformed",
+			signer:  s,
+			purpose: "verify",
+			token:   "garbage",
+			now:     now,
+			expErr:  ErrInvalidToken,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			subject, err := tt.signer.Verify(tt.purpose, tt.token, tt.now)
+
+			tests.Equal(t, err, tt.expErr)
+			tests.Equal(t, subject, tt.expSubject)
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package templates
+
+import (
+	"net/url"
+	"strconv"
+)
+
+type Pagination struct {
+	Page     int
+	PageSize int
+	Total    int
+	// base path and query params used to build page links
+	Path   string
+	Params url.Values
+}
+
+func NewPagination(page, pageSize, total int, path string, params url.Values) *Pagination {
+	return &Pagination{
+		Page:     page,
+		PageSize: pageSize,
+		Total:    total,
+		Path:     path,
+		Params:   params,
+	}
+}
+
+func (p *Pagination) TotalPages() int {
+	if p.Total == 0 || p.PageSize <= 0 {
+		return 1
+	}
+
+	return (p.Total + p.PageSize - 1) / p.PageSize
+}
+
+func (p *Pagination) HasPrev() bool {
+	return p.Page > 1
+}
+
+func (p *Pagination) HasNext() bool {
+	return p.Page < p.TotalPages()
+}
+
+func (p *Pagination) PrevPage() int {
+	return p.Page - 1
+}
+
+func (p *Pagination) NextPage() int {
+	return p.Page + 1
+}
+
+// URL returns link to given page keeping other query params
+func (p *Pagination) URL(page int) string {
+	params := url.Values{}
+
+	for k, v := range p.Params {
+		params[k] = v
+	}
+
+	params.Set("page", strconv.Itoa(page))
+
+	return p.Path + "?" + params.Encode()
+}
@@ -0,0 +1,68 @@
+package templates
+
+import (
+	"net/url"
+	"snippetbox/internal/tests"
+	"testing"
+)
+
+func Test_Pagination(t *testing.T) {
+	testCases := []struct {
+		name       string
+		page       int
+		total      int
+		totalPages int
+		hasPrev    bool
+		hasNext    bool
+	}{
+		{
+			name:       "Empty",
+			page:       1,
+			total:      0,
+			totalPages: 1,
+		},
+		{
+			name:       "First page",
+			page:       1,
+			total:      25,
+			totalPages: 3,
+			hasNext:    true,
+		},
+		{
+			name:       "Middle page",
+			page:       2,
+			total:      25,
+			totalPages: 3,
+			hasPrev:    true,
+			hasNext:    true,
+		},
+		{
+			name:       "Last page",
+			page:       3,
+			total:      30,
+			totalPages: 3,
+			hasPrev:    true,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPagination(tt.page, 10, tt.total, "/", nil)
+
+			tests.Equal(t, p.TotalPages(), tt.totalPages)
+			tests.Equal(t, p.HasPrev(), tt.hasPrev)
+			tests.Equal(t, p.HasNext(), tt.hasNext)
+		})
+	}
+}
+
+func Test_PaginationURL(t *testing.T) {
+	params := url.Values{}
+	params.Set("sort", "expires")
+
+	p := NewPagination(1, 10, 25, "/account/snippets", params)
+
+	tests.Equal(t, p.URL(2), "/account/snippets?page=2&sort=expires")
+	// params of pagination itself are not modified
+	tests.Equal(t, params.Get("page"), "")
+}
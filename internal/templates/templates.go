@@ -1,23 +1,38 @@
 package templates
 
 import (
+	"html/template"
 	"io/fs"
 	"path/filepath"
+	"snippetbox/internal/diff"
+	"snippetbox/internal/highlight"
 	"snippetbox/internal/models"
 	"snippetbox/ui"
-	"text/template"
 	"time"
 )
 
 type TemplateData struct {
-	Account         *models.User
-	Snippet         *models.Snippet
-	Snippets        []*models.Snippet
-	CurrentYear     int
-	Form            any
-	Flash           string
-	IsAuthenticated bool
-	CSRFToken       string
+	Account             *models.User
+	Snippet             *models.Snippet
+	Snippets            []*models.Snippet
+	Forks               int
+	Pagination          *Pagination
+	Query               string
+	Tokens              []*models.Token
+	Revisions           []*models.Revision
+	FromRevision        *models.Revision
+	ToRevision          *models.Revision
+	Diff                []diff.Hunk
+	NewToken            string
+	TwoFactorEnabled    bool
+	TOTPSecret          string
+	RecoveryCodes       []string
+	CurrentYear         int
+	Form                any
+	Flash               string
+	IsAuthenticated     bool
+	AuthenticatedUserID int
+	CSRFToken           string
 }
 
 func HumanDate(t time.Time) string {
@@ -30,6 +45,9 @@ func HumanDate(t time.Time) string {
 
 var functions = template.FuncMap{
 	"humanDate": HumanDate,
+	"highlight": highlight.HTML,
+	"languages": func() []string { return highlight.Languages },
+	"add":       func(a, b int) int { return a + b },
 }
 
 func NewTemplateCache() (map[string]*template.Template, error) {
@@ -1,7 +1,10 @@
 package templates
 
 import (
+	"bytes"
+	"snippetbox/internal/models"
 	"snippetbox/internal/tests"
+	"strings"
 	"testing"
 	"time"
 )
@@ -37,3 +40,64 @@ func Test_humanDate(t *testing.T) {
 		})
 	}
 }
+
+func Test_TemplatesEscapeUserContent(t *testing.T) {
+	cache, err := NewTemplateCache()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const payload = "<script>alert('xss')</script>"
+	const escaped = "&lt;script&gt;alert(&#39;xss&#39;)&lt;/script&gt;"
+
+	snippet := &models.Snippet{
+		ID:       1,
+		Title:    payload,
+		Content:  payload,
+		Language: "plaintext",
+		UserName: payload,
+		Created:  time.Now(),
+		Expires:  time.Now().Add(time.Hour),
+	}
+
+	testCases := []struct {
+		name string
+		page string
+		data *TemplateData
+	}{
+		{
+			name: "Snippet view",
+			page: "view.tmpl.html",
+			data: &TemplateData{Snippet: snippet},
+		},
+		{
+			name: "Home",
+			page: "home.tmpl.html",
+			data: &TemplateData{Snippets: []*models.Snippet{snippet}},
+		},
+		{
+			name: "Flash",
+			page: "about.tmpl.html",
+			data: &TemplateData{Flash: payload},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+
+			err := cache[tt.page].ExecuteTemplate(buf, "base", tt.data)
+
+			tests.NilError(t, err)
+
+			body := buf.String()
+
+			tests.StringContains(t, body, escaped)
+
+			if strings.Contains(body, payload) {
+				t.Errorf("got unescaped payload in %s", tt.page)
+			}
+		})
+	}
+}
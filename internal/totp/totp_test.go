@@ -0,0 +1,99 @@
+package totp
+
+import (
+	"encoding/base32"
+	"snippetbox/internal/tests"
+	"testing"
+	"time"
+)
+
+// SHA-1 seed from RFC 6238 appendix B
+var rfcSecret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func Test_Code(t *testing.T) {
+	// last 6 digits of RFC 6238 test vectors
+	testCases := []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "287082"},
+		{unix: 1111111109, want: "081804"},
+		{unix: 1111111111, want: "050471"},
+		{unix: 1234567890, want: "005924"},
+		{unix: 2000000000, want: "279037"},
+		{unix: 20000000000, want: "353130"},
+	}
+
+	for _, tt := range testCases {
+		code, err := Code(rfcSecret, Step(time.Unix(tt.unix, 0)))
+
+		tests.NilError(t, err)
+		tests.Equal(t, code, tt.want)
+	}
+}
+
+func Test_Validate(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+
+	testCases := []struct {
+		name    string
+		code    string
+		now     time.Time
+		expStep int64
+		expOK   bool
+	}{
+		{
+			name:    "Current step",
+			code:    "081804",
+			now:     now,
+			expStep: Step(now),
+			expOK:   true,
+		},
+		{
+			name:    "Previous step",
+			code:    "081804",
+			now:     now.Add(Period * time.Second),
+			expStep: Step(now),
+			expOK:   true,
+		},
+		{
+			name: "Too old",
+			code: "081804",
+			now:  now.Add(2 * Period * time.Second),
+		},
+		{
+			name: "Wrong code",
+			code: "123456",
+			now:  now,
+		},
+		{
+			name: "Wrong length",
+			code: "81804",
+			now:  now,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := Validate(rfcSecret, tt.code, tt.now)
+
+			tests.Equal(t, ok, tt.expOK)
+			tests.Equal(t, step, tt.expStep)
+		})
+	}
+}
+
+func Test_GenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	tests.NilError(t, err)
+	tests.Equal(t, len(secret), 32)
+
+	_, err = Code(secret, 1)
+	tests.NilError(t, err)
+}
+
+func Test_URL(t *testing.T) {
+	got := URL("Snippetbox", "user@test.com", "JBSWY3DPEHPK3PXP")
+
+	tests.Equal(t, got, "otpauth://totp/Snippetbox:user@test.com?issuer=Snippetbox&secret=JBSWY3DPEHPK3PXP")
+}